@@ -0,0 +1,151 @@
+package rui3
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+var commonBaudRates = []int{115200, 9600, 57600, 38400, 19200, 4800}
+
+// probeTimeout bounds each probe. A module answers AT within milliseconds.
+const probeTimeout = 500 * time.Millisecond
+
+type modeSetter interface {
+	SetMode(mode *serial.Mode) error
+}
+
+type modemControl interface {
+	SetDTR(dtr bool) error
+	SetRTS(rts bool) error
+}
+
+// transportAs finds an optional interface of the transport, looking through
+// wrappers such as the capture transport.
+func transportAs[T any](t Transport) (T, bool) {
+	for {
+		if v, ok := t.(T); ok {
+			return v, true
+		}
+		w, ok := t.(interface{ Unwrap() Transport })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		t = w.Unwrap()
+	}
+}
+
+func (r *RUI3) setHostMode(mode serial.Mode) error {
+	port, ok := transportAs[modeSetter](r.port)
+	if !ok {
+		return fmt.Errorf("transport does not support changing the line settings")
+	}
+
+	err := port.SetMode(&mode)
+	if err != nil {
+		return fmt.Errorf("failed to set port mode: %w", err)
+	}
+
+	r.mode = mode
+	r.ResetInputBuffer()
+
+	return nil
+}
+
+// SetBaudRate switches the module UART with AT+BAUD and then the host port.
+// If the module does not answer at the new rate the host port is switched
+// back.
+func (r *RUI3) SetBaudRate(baud int) error {
+	if !slices.Contains(commonBaudRates, baud) {
+		return fmt.Errorf("invalid baud rate: %d", baud)
+	}
+
+	if _, ok := transportAs[modeSetter](r.port); !ok {
+		return fmt.Errorf("transport does not support changing the line settings")
+	}
+
+	err := r.Set(context.Background(), "AT+BAUD", baud)
+	if err != nil {
+		return err
+	}
+
+	err = r.Drain()
+	if err != nil {
+		return fmt.Errorf("failed to drain port: %w", err)
+	}
+
+	previous := r.mode
+	mode := r.mode
+	mode.BaudRate = baud
+
+	err = r.setHostMode(mode)
+	if err != nil {
+		return err
+	}
+
+	if r.probe(context.Background()) {
+		return nil
+	}
+
+	err = r.setHostMode(previous)
+	if err != nil {
+		return fmt.Errorf("module not responding at %d baud and failed to restore %d baud: %w", baud, previous.BaudRate, err)
+	}
+
+	return fmt.Errorf("module not responding at %d baud", baud)
+}
+
+// DetectBaudRate tries the common RUI3 baud rates until the module answers
+// AT, or turns out to be locked, and leaves the host port at that rate.
+func (r *RUI3) DetectBaudRate(ctx context.Context) (int, error) {
+	rates := []int{r.mode.BaudRate}
+	for _, baud := range commonBaudRates {
+		if baud != r.mode.BaudRate {
+			rates = append(rates, baud)
+		}
+	}
+
+	for _, baud := range rates {
+		if ctx.Err() != nil {
+			return 0, ctx.Err()
+		}
+
+		mode := r.mode
+		mode.BaudRate = baud
+		err := r.setHostMode(mode)
+		if err != nil {
+			return 0, err
+		}
+
+		if r.probe(ctx) {
+			return baud, nil
+		}
+		// a locked module only answers AT+PWORD, WithPassword unlocks it
+		// once the rate is found
+		if r.probeLocked(ctx) {
+			r.locked = true
+			return baud, nil
+		}
+	}
+
+	return 0, fmt.Errorf("module not responding at any of %v baud", rates)
+}
+
+// probe sends AT twice, since the first command after a rate change often
+// arrives garbled.
+func (r *RUI3) probe(ctx context.Context) bool {
+	for range 2 {
+		attemptCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+		response, err := r.Exec(attemptCtx, "AT")
+		cancel()
+		if err == nil && strings.Contains(response, "OK") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package rui3
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	CaptureTX = "tx"
+	CaptureRX = "rx"
+)
+
+// CaptureRecord is one line of a JSONL capture. Data holds the bytes that
+// crossed the transport, split into lines with keys and passwords redacted.
+type CaptureRecord struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"dir"`
+	Data      []byte    `json:"data"`
+}
+
+// WithCapture records every line sent to and received from the module to
+// w as JSONL CaptureRecords.
+func WithCapture(w io.Writer) Option {
+	return func(o *options) {
+		o.capture = w
+	}
+}
+
+type captureTransport struct {
+	Transport
+
+	mu      sync.Mutex
+	enc     *json.Encoder
+	pending map[string][]byte
+}
+
+func newCaptureTransport(port Transport, w io.Writer) *captureTransport {
+	return &captureTransport{
+		Transport: port,
+		enc:       json.NewEncoder(w),
+		pending:   make(map[string][]byte),
+	}
+}
+
+// Unwrap gives access to the optional interfaces of the wrapped transport.
+func (t *captureTransport) Unwrap() Transport {
+	return t.Transport
+}
+
+// record buffers p until a line is complete, so a key is never written to
+// the capture before the whole line can be redacted.
+func (t *captureTransport) record(dir string, p []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	buf := append(t.pending[dir], p...)
+	for {
+		end := bytes.IndexByte(buf, '\n')
+		if end < 0 {
+			break
+		}
+		t.encode(dir, buf[:end+1])
+		buf = buf[end+1:]
+	}
+	t.pending[dir] = bytes.Clone(buf)
+}
+
+// encode writes one record, called with t.mu held.
+func (t *captureTransport) encode(dir string, data []byte) {
+	// a broken capture must never break the session itself
+	t.enc.Encode(CaptureRecord{
+		Time:      time.Now(),
+		Direction: dir,
+		Data:      redactLine(data),
+	})
+}
+
+func (t *captureTransport) Read(p []byte) (int, error) {
+	n, err := t.Transport.Read(p)
+	if n > 0 {
+		t.record(CaptureRX, p[:n])
+	}
+	return n, err
+}
+
+func (t *captureTransport) Write(p []byte) (int, error) {
+	n, err := t.Transport.Write(p)
+	if n > 0 {
+		t.record(CaptureTX, p[:n])
+	}
+	return n, err
+}
+
+func (t *captureTransport) Close() error {
+	t.mu.Lock()
+	for _, dir := range []string{CaptureTX, CaptureRX} {
+		if len(t.pending[dir]) > 0 {
+			t.encode(dir, t.pending[dir])
+			t.pending[dir] = nil
+		}
+	}
+	t.mu.Unlock()
+
+	return t.Transport.Close()
+}
+
+// redactLine redacts a line including its line ending.
+func redactLine(line []byte) []byte {
+	text := string(line)
+	body := strings.TrimRight(text, "\r\n")
+	return []byte(redact(body) + text[len(body):])
+}
+
+// ReplayTransport plays a capture back. Received data is served in the
+// recorded order and every write has to match the recorded transmission,
+// so a session replayed through NewWithPort behaves like the original.
+type ReplayTransport struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	records []CaptureRecord
+	pos     int
+	offset  int
+	err     error
+	closed  bool
+}
+
+func NewReplayTransport(r io.Reader) (*ReplayTransport, error) {
+	t := &ReplayTransport{}
+	t.cond = sync.NewCond(&t.mu)
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var record CaptureRecord
+		err := json.Unmarshal(scanner.Bytes(), &record)
+		if err != nil {
+			return nil, fmt.Errorf("invalid capture record %d: %w", len(t.records)+1, err)
+		}
+		if record.Direction != CaptureTX && record.Direction != CaptureRX {
+			return nil, fmt.Errorf("invalid capture direction %q", record.Direction)
+		}
+		if len(record.Data) > 0 {
+			t.records = append(t.records, record)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read capture: %w", err)
+	}
+
+	return t, nil
+}
+
+func (t *ReplayTransport) advance(n int) {
+	t.offset += n
+	if t.offset == len(t.records[t.pos].Data) {
+		t.pos++
+		t.offset = 0
+	}
+	t.cond.Broadcast()
+}
+
+func (t *ReplayTransport) Read(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for !t.closed && t.err == nil && t.pos < len(t.records) && t.records[t.pos].Direction != CaptureRX {
+		t.cond.Wait()
+	}
+
+	switch {
+	case t.closed:
+		return 0, io.ErrClosedPipe
+	case t.err != nil:
+		return 0, t.err
+	case t.pos == len(t.records):
+		return 0, io.EOF
+	}
+
+	n := copy(p, t.records[t.pos].Data[t.offset:])
+	t.advance(n)
+
+	return n, nil
+}
+
+func (t *ReplayTransport) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	written := 0
+	for written < len(p) {
+		// let the reader catch up with data recorded before this write
+		for !t.closed && t.pos < len(t.records) && t.records[t.pos].Direction != CaptureTX {
+			t.cond.Wait()
+		}
+		if t.closed {
+			return written, io.ErrClosedPipe
+		}
+		if t.pos == len(t.records) {
+			t.err = fmt.Errorf("replay mismatch: unexpected write %q after end of capture", p[written:])
+			t.cond.Broadcast()
+			return written, t.err
+		}
+
+		expected := t.records[t.pos].Data[t.offset:]
+
+		// keys were redacted when the line was recorded, so the written
+		// line is matched in its redacted form
+		if t.offset == 0 && bytes.Contains(expected, []byte(redacted)) {
+			line := p[written:]
+			if end := bytes.IndexByte(line, '\n'); end >= 0 {
+				line = line[:end+1]
+			}
+			if !bytes.Equal(redactLine(line), expected) {
+				t.err = fmt.Errorf("replay mismatch: expected %q, got %q", expected, redactLine(line))
+				t.cond.Broadcast()
+				return written, t.err
+			}
+			written += len(line)
+			t.advance(len(expected))
+			continue
+		}
+
+		n := min(len(expected), len(p)-written)
+		if !bytes.Equal(expected[:n], p[written:written+n]) {
+			t.err = fmt.Errorf("replay mismatch: expected %q, got %q", expected, p[written:])
+			t.cond.Broadcast()
+			return written, t.err
+		}
+
+		written += n
+		t.advance(n)
+	}
+
+	return written, nil
+}
+
+// Done reports whether every recorded byte has been replayed.
+func (t *ReplayTransport) Done() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pos == len(t.records)
+}
+
+func (t *ReplayTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.closed = true
+	t.cond.Broadcast()
+	return nil
+}
+
+func (t *ReplayTransport) SetReadTimeout(timeout time.Duration) error { return nil }
+func (t *ReplayTransport) ResetInputBuffer() error                    { return nil }
+func (t *ReplayTransport) ResetOutputBuffer() error                   { return nil }
+func (t *ReplayTransport) Drain() error                               { return nil }
@@ -0,0 +1,114 @@
+package rui3_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"tencorvids/rui3-go"
+	"tencorvids/rui3-go/sim"
+)
+
+const appKey = "00112233445566778899AABBCCDDEEFF"
+
+// session runs the calls captured and replayed by the tests.
+func session(t *testing.T, r *rui3.RUI3) (devEUI string, band rui3.RegionBand) {
+	t.Helper()
+
+	devEUI, err := r.GetDevEUI()
+	if err != nil {
+		t.Fatalf("GetDevEUI: %v", err)
+	}
+	err = r.SetAppKey(appKey)
+	if err != nil {
+		t.Fatalf("SetAppKey: %v", err)
+	}
+	band, err = r.GetRegionBand()
+	if err != nil {
+		t.Fatalf("GetRegionBand: %v", err)
+	}
+
+	return devEUI, band
+}
+
+func capture(t *testing.T) (*bytes.Buffer, string, rui3.RegionBand) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	r := rui3.NewWithPort(sim.New(), rui3.WithCapture(&buf))
+	devEUI, band := session(t, r)
+	r.Close()
+
+	return &buf, devEUI, band
+}
+
+func TestReplayCapture(t *testing.T) {
+	recorded, devEUI, band := capture(t)
+
+	replay, err := rui3.NewReplayTransport(recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rui3.NewWithPort(replay)
+	defer r.Close()
+
+	gotDevEUI, gotBand := session(t, r)
+	if gotDevEUI != devEUI || gotBand != band {
+		t.Errorf("replayed %s %v, recorded %s %v", gotDevEUI, gotBand, devEUI, band)
+	}
+	if !replay.Done() {
+		t.Error("capture not fully replayed")
+	}
+}
+
+func TestReplayMismatch(t *testing.T) {
+	recorded, _, _ := capture(t)
+
+	replay, err := rui3.NewReplayTransport(recorded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := rui3.NewWithPort(replay)
+	defer r.Close()
+
+	_, err = r.GetAppEUI()
+	if err == nil || !strings.Contains(err.Error(), "replay mismatch") {
+		t.Errorf("GetAppEUI = %v, want a replay mismatch", err)
+	}
+}
+
+func TestCaptureRedactsKeys(t *testing.T) {
+	recorded, _, _ := capture(t)
+
+	dec := json.NewDecoder(recorded)
+	for dec.More() {
+		var record rui3.CaptureRecord
+		err := dec.Decode(&record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(record.Data, []byte(appKey)) {
+			t.Errorf("%s record %q contains the AppKey", record.Direction, record.Data)
+		}
+	}
+}
+
+func TestCaptureKeepsTransportCapabilities(t *testing.T) {
+	// a replay can not change line settings, AT+BAUD must not be sent
+	replay, err := rui3.NewReplayTransport(strings.NewReader(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	r := rui3.NewWithPort(replay, rui3.WithCapture(&buf))
+	defer r.Close()
+
+	err = r.SetBaudRate(9600)
+	if err == nil || !strings.Contains(err.Error(), "does not support") {
+		t.Errorf("SetBaudRate = %v, want an unsupported transport error", err)
+	}
+	if buf.Len() > 0 {
+		t.Errorf("sent %q", buf.String())
+	}
+}
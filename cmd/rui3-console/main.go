@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"tencorvids/rui3-go"
+	"time"
+
+	"golang.org/x/term"
+)
+
+var macros = []string{":help", ":info", ":keys", ":join", ":reset", ":echo", ":quit"}
+
+type console struct {
+	rui        *rui3.RUI3
+	timeout    time.Duration
+	term       *term.Terminal
+	out        io.Writer
+	transcript io.Writer
+
+	mu sync.Mutex
+}
+
+func main() {
+	portName := flag.String("port", "", "serial port, discovered automatically when empty")
+	baud := flag.Int("baud", 115200, "baud rate")
+	timeout := flag.Duration("timeout", 10*time.Second, "response timeout for raw commands")
+	logPath := flag.String("log", "", "append the session transcript to this file")
+	flag.Parse()
+
+	err := run(*portName, *baud, *timeout, *logPath)
+	if err != nil {
+		slog.Error("Console failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(portName string, baud int, timeout time.Duration, logPath string) error {
+	if portName == "" {
+		modules, err := rui3.Discover(context.Background(), rui3.WithBaudRate(baud))
+		if err != nil {
+			return fmt.Errorf("failed to discover modules: %w", err)
+		}
+		if len(modules) == 0 {
+			return errors.New("no modules found")
+		}
+		portName = modules[0].Port
+	}
+
+	rui, err := rui3.New(portName, rui3.WithBaudRate(baud))
+	if err != nil {
+		return err
+	}
+	defer rui.Close()
+
+	c := &console{
+		rui:     rui,
+		timeout: timeout,
+		out:     os.Stdout,
+	}
+
+	if logPath != "" {
+		f, err := os.OpenFile(logPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open transcript: %w", err)
+		}
+		defer f.Close()
+		c.transcript = f
+	}
+
+	// the terminal is set up before events are printed from another
+	// goroutine
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	if interactive {
+		state, err := term.MakeRaw(int(os.Stdin.Fd()))
+		if err != nil {
+			return fmt.Errorf("failed to enter raw mode: %w", err)
+		}
+		defer term.Restore(int(os.Stdin.Fd()), state)
+
+		c.term = term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{os.Stdin, os.Stdout}, "rui3> ")
+		c.term.AutoCompleteCallback = complete
+		c.out = c.term
+	}
+
+	events, unsubscribe := rui.Subscribe()
+	defer unsubscribe()
+	go func() {
+		for ev := range events {
+			c.print(c.color("event"), "!", ev.Raw)
+		}
+	}()
+
+	if !interactive {
+		return c.runScript(os.Stdin)
+	}
+
+	c.print(nil, "", fmt.Sprintf("Connected to %s, type :help for macros", portName))
+
+	for {
+		line, err := c.term.ReadLine()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+
+		if !c.execute(line) {
+			return nil
+		}
+	}
+}
+
+func (c *console) runScript(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		c.print(nil, ">", line)
+		if !c.execute(line) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// execute runs one console line and reports whether the session goes on.
+func (c *console) execute(line string) bool {
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return true
+	}
+	c.record(">", line)
+
+	if strings.HasPrefix(line, ":") {
+		return c.macro(line)
+	}
+
+	err := c.rui.SendRawCommand(line)
+	if err != nil {
+		c.print(c.color("error"), "<", err.Error())
+		return true
+	}
+
+	response, err := c.rui.RecvResponse(c.timeout)
+	for l := range strings.SplitSeq(strings.TrimSpace(response), "\n") {
+		// events are printed as they arrive by the subscriber
+		if l == "" || strings.HasPrefix(l, "+EVT:") {
+			continue
+		}
+		kind := "response"
+		if l == "OK" {
+			kind = "ok"
+		}
+		c.print(c.color(kind), "<", l)
+	}
+	if err != nil {
+		c.print(c.color("error"), "<", err.Error())
+	}
+
+	return true
+}
+
+func (c *console) macro(line string) bool {
+	fields := strings.Fields(line)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	switch fields[0] {
+	case ":quit", ":exit":
+		return false
+	case ":help":
+		c.print(nil, "", ":info         show device information")
+		c.print(nil, "", ":keys         show DevEUI and AppEUI")
+		c.print(nil, "", ":join         join the network and wait for the result")
+		c.print(nil, "", ":reset        restart the module and show the boot message")
+		c.print(nil, "", ":echo on|off  set command echo")
+		c.print(nil, "", ":quit         leave the console")
+	case ":info":
+		info, err := c.rui.GetDeviceInfo(ctx)
+		if err != nil {
+			c.print(c.color("error"), "<", err.Error())
+			return true
+		}
+		c.print(nil, "<", "Model:      "+info.HardwareModel)
+		c.print(nil, "<", "Serial:     "+info.SerialNumber)
+		c.print(nil, "<", "Firmware:   "+info.Firmware.Raw)
+		c.print(nil, "<", "API:        "+info.API.Raw)
+		c.print(nil, "<", "Bootloader: "+info.Bootloader.Raw)
+		c.print(nil, "<", fmt.Sprintf("Battery:    %.2fV", info.BatteryVoltage))
+	case ":keys":
+		devEUI, err := c.rui.GetDevEUI()
+		if err != nil {
+			c.print(c.color("error"), "<", err.Error())
+			return true
+		}
+		appEUI, err := c.rui.GetAppEUI()
+		if err != nil {
+			c.print(c.color("error"), "<", err.Error())
+			return true
+		}
+		c.print(nil, "<", "DevEUI: "+devEUI)
+		c.print(nil, "<", "AppEUI: "+appEUI)
+	case ":join":
+		c.join(ctx)
+	case ":reset":
+		banner, err := c.rui.ResetMCU(ctx)
+		for l := range strings.SplitSeq(strings.TrimSpace(banner), "\n") {
+			c.print(nil, "<", l)
+		}
+		if err != nil {
+			c.print(c.color("error"), "<", err.Error())
+		}
+	case ":echo":
+		if len(fields) != 2 || (fields[1] != "on" && fields[1] != "off") {
+			c.print(c.color("error"), "<", "usage: :echo on|off")
+			return true
+		}
+		err := c.rui.SetEcho(fields[1] == "on")
+		if err != nil {
+			c.print(c.color("error"), "<", err.Error())
+			return true
+		}
+		c.print(c.color("ok"), "<", "OK")
+	default:
+		c.print(c.color("error"), "<", "unknown macro "+fields[0]+", type :help")
+	}
+
+	return true
+}
+
+func (c *console) join(ctx context.Context) {
+	events, unsubscribe := c.rui.Subscribe()
+	defer unsubscribe()
+
+	err := c.rui.JoinNetworkWithParams(true, false, 8, 0)
+	if err != nil {
+		c.print(c.color("error"), "<", err.Error())
+		return
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			c.print(c.color("error"), "<", "timeout waiting for join")
+			return
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			switch ev.Name {
+			case "JOINED":
+				c.print(c.color("ok"), "<", "Joined")
+				return
+			case "JOIN_FAILED_RX_TIMEOUT", "JOIN_FAILED":
+				c.print(c.color("error"), "<", "Join failed")
+				return
+			}
+		}
+	}
+}
+
+func (c *console) color(kind string) []byte {
+	if c.term == nil {
+		return nil
+	}
+	switch kind {
+	case "ok":
+		return c.term.Escape.Green
+	case "error":
+		return c.term.Escape.Red
+	case "event":
+		return c.term.Escape.Cyan
+	}
+	return nil
+}
+
+func (c *console) print(color []byte, prefix string, line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	text := line
+	if prefix != "" && c.term == nil {
+		text = prefix + " " + line
+	}
+	if color != nil {
+		text = string(color) + text + string(c.term.Escape.Reset)
+	}
+	fmt.Fprintln(c.out, text)
+
+	if prefix != ">" {
+		c.recordLocked(prefix, line)
+	}
+}
+
+func (c *console) record(prefix string, line string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recordLocked(prefix, line)
+}
+
+func (c *console) recordLocked(prefix string, line string) {
+	if c.transcript == nil {
+		return
+	}
+	if prefix == "" {
+		prefix = "#"
+	}
+	fmt.Fprintf(c.transcript, "%s %s %s\n", time.Now().Format(time.RFC3339Nano), prefix, line)
+}
+
+// complete expands the command under the cursor to the longest prefix
+// shared by the matching RUI3 commands and macros.
+func complete(line string, pos int, key rune) (string, int, bool) {
+	if key != '\t' || strings.ContainsAny(line[:pos], " =") {
+		return "", 0, false
+	}
+
+	prefix := strings.ToUpper(line[:pos])
+	candidates := rui3.KnownCommands
+	if strings.HasPrefix(line, ":") {
+		prefix = line[:pos]
+		candidates = macros
+	}
+
+	var matches []string
+	for _, cmd := range candidates {
+		if strings.HasPrefix(cmd, prefix) {
+			matches = append(matches, cmd)
+		}
+	}
+	if len(matches) == 0 {
+		return "", 0, false
+	}
+
+	common := slices.MinFunc(matches, func(a, b string) int { return len(a) - len(b) })
+	for _, m := range matches {
+		for !strings.HasPrefix(m, common) {
+			common = common[:len(common)-1]
+		}
+	}
+
+	return common + line[pos:], len(common), true
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"tencorvids/rui3-go"
+
+	"gopkg.in/yaml.v3"
+)
+
+func (a *app) config(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: rui3 config get|apply")
+	}
+
+	switch args[0] {
+	case "get":
+		return a.configGet(ctx, rui, args[1:])
+	case "apply":
+		return a.configApply(ctx, rui, args[1:])
+	}
+
+	return fmt.Errorf("unknown config command %q", args[0])
+}
+
+func (a *app) configGet(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	var reveal bool
+	_, err := subcommand("config get", args, func(f *flag.FlagSet) {
+		f.BoolVar(&reveal, "reveal", false, "include the AppKey and session keys")
+	})
+	if err != nil {
+		return err
+	}
+
+	cfg, err := rui.ReadConfig(ctx)
+	if err != nil {
+		return err
+	}
+
+	out, err := exportConfig(cfg, reveal)
+	if err != nil {
+		return err
+	}
+
+	var encodeErr error
+	err = a.print(out, func() {
+		enc := yaml.NewEncoder(os.Stdout)
+		enc.SetIndent(2)
+		encodeErr = enc.Encode(out)
+	})
+	if err != nil {
+		return err
+	}
+	return encodeErr
+}
+
+// exportConfig leaves the secret keys out of cfg, a redacted key could not
+// be applied again. With reveal they are included in plain text.
+func exportConfig(cfg *rui3.Config, reveal bool) (any, error) {
+	secrets := map[string]*rui3.Secret{
+		"appKey":  cfg.Keys.AppKey,
+		"appSKey": cfg.Keys.AppSKey,
+		"nwkSKey": cfg.Keys.NwkSKey,
+	}
+	cfg.Keys.AppKey, cfg.Keys.AppSKey, cfg.Keys.NwkSKey = nil, nil, nil
+	if !reveal {
+		return cfg, nil
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	err = json.Unmarshal(data, &out)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, _ := out["keys"].(map[string]any)
+	if keys == nil {
+		keys = make(map[string]any)
+	}
+	for name, secret := range secrets {
+		if secret != nil {
+			keys[name] = secret.Reveal()
+		}
+	}
+	if len(keys) > 0 {
+		out["keys"] = keys
+	}
+
+	return out, nil
+}
+
+func (a *app) configApply(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	var dryRun bool
+	flags, err := subcommand("config apply", args, func(f *flag.FlagSet) {
+		f.BoolVar(&dryRun, "dry-run", false, "only show the changes")
+	})
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: rui3 config apply [--dry-run] FILE")
+	}
+
+	cfg, err := rui3.LoadConfig(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var changes []rui3.Change
+	if dryRun {
+		current, err := rui.ReadConfig(ctx)
+		if err != nil {
+			return err
+		}
+		changes = rui3.DiffConfig(current, cfg)
+	} else {
+		changes, err = rui.ApplyConfig(ctx, cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	if changes == nil {
+		changes = []rui3.Change{}
+	}
+	return a.print(changes, func() {
+		if len(changes) == 0 {
+			fmt.Println("up to date")
+		}
+		for _, c := range changes {
+			fmt.Println(c)
+		}
+	})
+}
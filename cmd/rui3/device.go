@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"tencorvids/rui3-go"
+	"tencorvids/rui3-go/dfu"
+)
+
+func (a *app) info(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	info, err := rui.GetDeviceInfo(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.print(info, func() {
+		fmt.Printf("Model:       %s\n", info.HardwareModel)
+		fmt.Printf("Hardware ID: %s\n", info.HardwareID)
+		fmt.Printf("Serial:      %s\n", info.SerialNumber)
+		fmt.Printf("UID:         %s\n", info.UID)
+		fmt.Printf("Alias:       %s\n", info.Alias)
+		fmt.Printf("Firmware:    %s\n", info.Firmware.Raw)
+		fmt.Printf("API:         %s\n", info.API.Raw)
+		fmt.Printf("Bootloader:  %s\n", info.Bootloader.Raw)
+		fmt.Printf("CLI:         %s\n", info.CLI.Raw)
+		fmt.Printf("Build time:  %s\n", info.BuildTime)
+		fmt.Printf("Repository:  %s\n", info.RepoInfo)
+		fmt.Printf("Battery:     %.3fV\n", info.BatteryVoltage)
+	})
+}
+
+func (a *app) reset(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	var factory bool
+	_, err := subcommand("reset", args, func(f *flag.FlagSet) {
+		f.BoolVar(&factory, "factory", false, "restore factory defaults")
+	})
+	if err != nil {
+		return err
+	}
+
+	reset := rui.ResetMCU
+	if factory {
+		reset = rui.ResetFactoryDefaults
+	}
+
+	banner, err := reset(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.print(map[string]string{"banner": banner}, func() {
+		fmt.Print(banner)
+	})
+}
+
+func (a *app) lock(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	var password string
+	_, err := subcommand("lock", args, func(f *flag.FlagSet) {
+		f.StringVar(&password, "password", "", "set the password, 1 to 8 letters and digits")
+	})
+	if err != nil {
+		return err
+	}
+
+	if password != "" {
+		err = rui.SetPassword(ctx, rui3.Secret(password))
+		if err != nil {
+			return err
+		}
+	}
+
+	return rui.Lock(ctx)
+}
+
+func (a *app) unlock(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	password := os.Getenv("RUI3_PASSWORD")
+	if password == "" {
+		return errors.New("RUI3_PASSWORD is not set")
+	}
+	return rui.Unlock(ctx, rui3.Secret(password))
+}
+
+func (a *app) update(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	flags, err := subcommand("update", args, nil)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: rui3 update IMAGE")
+	}
+
+	progress := func(p dfu.Progress) {
+		fmt.Fprintf(os.Stderr, "\r%d/%d bytes", p.Sent, p.Total)
+	}
+	err = dfu.UpdateFile(ctx, rui, flags.Arg(0), dfu.WithProgress(progress))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return err
+	}
+
+	version, err := rui.GetFirmwareVersion()
+	if err != nil {
+		return err
+	}
+
+	return a.print(map[string]string{"firmware": version}, func() {
+		fmt.Printf("Firmware: %s\n", version)
+	})
+}
+
+func (a *app) commands(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	commands, err := rui.ListCommands(ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.print(commands, func() {
+		for _, cmd := range slices.Sorted(maps.Keys(commands)) {
+			fmt.Printf("%-14s %s\n", cmd, commands[cmd])
+		}
+	})
+}
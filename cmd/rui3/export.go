@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"tencorvids/rui3-go/provision"
+)
+
+func (a *app) export(args []string) error {
+	var format, keyStore string
+	flags, err := subcommand("export", args, func(f *flag.FlagSet) {
+		f.StringVar(&format, "format", "tts", "tts or chirpstack")
+		f.StringVar(&keyStore, "keystore", "", "take redacted keys from an encrypted key store, the passphrase is read from RUI3_KEYSTORE_PASSPHRASE")
+	})
+	if err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: rui3 export [--format tts|chirpstack] [--keystore FILE] RECORDS")
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	records, err := provision.ReadRecords(f)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", flags.Arg(0), err)
+	}
+
+	if keyStore != "" {
+		store, err := openKeyStore(keyStore)
+		if err != nil {
+			return err
+		}
+		err = provision.FillKeys(records, store)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch format {
+	case "tts":
+		return provision.WriteTTS(os.Stdout, records)
+	case "chirpstack":
+		return provision.WriteChirpStack(os.Stdout, records)
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"tencorvids/rui3-go"
+)
+
+type keys struct {
+	DevEUI string `json:"devEUI"`
+	AppEUI string `json:"appEUI"`
+	AppKey string `json:"appKey"`
+}
+
+func (a *app) keys(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: rui3 keys get|set")
+	}
+
+	switch args[0] {
+	case "get":
+		return a.keysGet(rui, args[1:])
+	case "set":
+		return a.keysSet(rui, args[1:])
+	}
+
+	return fmt.Errorf("unknown keys command %q", args[0])
+}
+
+func (a *app) keysGet(rui *rui3.RUI3, args []string) error {
+	var reveal bool
+	_, err := subcommand("keys get", args, func(f *flag.FlagSet) {
+		f.BoolVar(&reveal, "reveal", false, "show the AppKey instead of redacting it")
+	})
+	if err != nil {
+		return err
+	}
+
+	var k keys
+
+	k.DevEUI, err = rui.GetDevEUI()
+	if err != nil {
+		return err
+	}
+	k.AppEUI, err = rui.GetAppEUI()
+	if err != nil {
+		return err
+	}
+	appKey, err := rui.GetAppKey()
+	if err != nil {
+		return err
+	}
+	k.AppKey = appKey.String()
+	if reveal {
+		k.AppKey = appKey.Reveal()
+	}
+
+	return a.print(k, func() {
+		fmt.Printf("DevEUI: %s\n", k.DevEUI)
+		fmt.Printf("AppEUI: %s\n", k.AppEUI)
+		fmt.Printf("AppKey: %s\n", k.AppKey)
+	})
+}
+
+func (a *app) keysSet(rui *rui3.RUI3, args []string) error {
+	var k keys
+	_, err := subcommand("keys set", args, func(f *flag.FlagSet) {
+		f.StringVar(&k.DevEUI, "deveui", "", "DevEUI as 16 hex digits")
+		f.StringVar(&k.AppEUI, "appeui", "", "AppEUI/JoinEUI as 16 hex digits")
+		f.StringVar(&k.AppKey, "appkey", "", "AppKey as 32 hex digits")
+	})
+	if err != nil {
+		return err
+	}
+
+	if k.DevEUI == "" && k.AppEUI == "" && k.AppKey == "" {
+		return errors.New("nothing to set, use --deveui, --appeui or --appkey")
+	}
+
+	if k.DevEUI != "" {
+		err = rui.SetDevEUI(k.DevEUI)
+		if err != nil {
+			return err
+		}
+	}
+	if k.AppEUI != "" {
+		err = rui.SetAppEUI(k.AppEUI)
+		if err != nil {
+			return err
+		}
+	}
+	if k.AppKey != "" {
+		err = rui.SetAppKey(rui3.Secret(k.AppKey))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"tencorvids/rui3-go"
+	"time"
+)
+
+func (a *app) join(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	var timeout time.Duration
+	_, err := subcommand("join", args, func(f *flag.FlagSet) {
+		f.DurationVar(&timeout, "timeout", time.Minute, "time to wait for the join")
+	})
+	if err != nil {
+		return err
+	}
+
+	events, unsubscribe := rui.Subscribe()
+	defer unsubscribe()
+
+	err = rui.JoinNetworkWithParams(true, false, 8, 0)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("join not confirmed: %w", ctx.Err())
+		case ev, ok := <-events:
+			if !ok {
+				return errors.New("connection lost")
+			}
+			switch {
+			case ev.Name == "JOINED":
+				return a.print(map[string]bool{"joined": true}, func() {
+					fmt.Println("Joined")
+				})
+			case strings.HasPrefix(ev.Name, "JOIN_FAILED"):
+				return fmt.Errorf("join failed: %s", ev.Raw)
+			}
+		}
+	}
+}
+
+func (a *app) send(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	var port int
+	var hexPayload string
+	flags, err := subcommand("send", args, func(f *flag.FlagSet) {
+		f.IntVar(&port, "port", 1, "LoRaWAN application port")
+		f.StringVar(&hexPayload, "hex", "", "payload as hex, instead of TEXT")
+	})
+	if err != nil {
+		return err
+	}
+
+	payload := []byte(strings.Join(flags.Args(), " "))
+	if hexPayload != "" {
+		payload, err = hex.DecodeString(hexPayload)
+		if err != nil {
+			return fmt.Errorf("invalid hex payload: %w", err)
+		}
+	}
+	if len(payload) == 0 {
+		return errors.New("nothing to send, pass TEXT or --hex")
+	}
+
+	return rui.SendOnPort(port, payload)
+}
+
+func (a *app) listen(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	var duration time.Duration
+	_, err := subcommand("listen", args, func(f *flag.FlagSet) {
+		f.DurationVar(&duration, "duration", 0, "stop after this long, 0 listens until interrupted")
+	})
+	if err != nil {
+		return err
+	}
+
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	events, unsubscribe := rui.Subscribe()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev, ok := <-events:
+			if !ok {
+				return errors.New("connection lost")
+			}
+			err := a.print(ev, func() {
+				if d, ok := rui3.ParseDownlink(ev); ok {
+					fmt.Printf("%s downlink port=%d rssi=%d snr=%d payload=%X\n",
+						ev.Time.Format(time.RFC3339), d.Port, d.RSSI, d.SNR, d.Payload)
+					return
+				}
+				fmt.Printf("%s %s\n", ev.Time.Format(time.RFC3339), ev.Raw)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
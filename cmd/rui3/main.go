@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"tencorvids/rui3-go"
+)
+
+const usage = `Usage: rui3 [--port PORT] [--baud BAUD] [--json] <command> [arguments]
+
+Commands:
+  info                          show device information
+  commands                      list the commands the module supports
+  keys get [--reveal]           show DevEUI, AppEUI and AppKey
+  keys set [--deveui] [--appeui] [--appkey]
+                                write OTAA keys
+  join [--timeout]              join the network and wait for the result
+  send [--port] [--hex] [TEXT]  send an uplink
+  listen [--duration]           print events until interrupted
+  p2p send [--switch-mode] --hex HEX
+                                send a P2P packet
+  p2p recv [--switch-mode] [--window]
+                                wait for a P2P packet, --switch-mode
+                                restarts a LoRaWAN module in P2P mode
+  reset [--factory]             restart the module
+  update IMAGE                  flash a DFU package (.zip) or .bin image
+                                through the bootloader
+  provision [--joineui HEX] [--random-joineui] [--out FILE]
+            [--keystore FILE] [--reveal]
+                                write a random AppKey and record the keys,
+                                the AppKey in plain text only with --reveal,
+                                one of --keystore and --reveal is required
+  export [--format tts|chirpstack] [--keystore FILE] RECORDS
+                                convert provisioning records for import
+                                on a network server, redacted keys are
+                                taken from the key store
+  lock [--password PW]          lock the AT interface, setting the password first
+                                when given
+  unlock                        unlock the AT interface with RUI3_PASSWORD
+  config get [--reveal]         show the LoRaWAN settings as YAML, the
+                                keys only with --reveal
+  config apply [--dry-run] FILE write the settings that differ from a
+                                YAML or JSON profile
+
+PORT may be a device name or a tcp://, unix:// or rfc2217:// URL and is
+discovered automatically when empty. A locked module is unlocked with the
+password in RUI3_PASSWORD.
+`
+
+type app struct {
+	portName string
+	baud     int
+	json     bool
+}
+
+func main() {
+	var a app
+	flags := flag.NewFlagSet("rui3", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flags.StringVar(&a.portName, "port", "", "serial port or URL")
+	flags.IntVar(&a.baud, "baud", 115200, "baud rate")
+	flags.BoolVar(&a.json, "json", false, "print JSON")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := a.run(ctx, flags.Arg(0), flags.Args()[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "rui3: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (a *app) run(ctx context.Context, cmd string, args []string) error {
+	commands := map[string]func(context.Context, *rui3.RUI3, []string) error{
+		"info":      a.info,
+		"commands":  a.commands,
+		"keys":      a.keys,
+		"join":      a.join,
+		"send":      a.send,
+		"listen":    a.listen,
+		"p2p":       a.p2p,
+		"reset":     a.reset,
+		"config":    a.config,
+		"provision": a.provision,
+		"update":    a.update,
+		"lock":      a.lock,
+		"unlock":    a.unlock,
+	}
+
+	// commands that work on files only, without a module
+	local := map[string]func([]string) error{
+		"export": a.export,
+	}
+	if handler, ok := local[cmd]; ok {
+		return handler(args)
+	}
+
+	handler, ok := commands[cmd]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+
+	// unlock sends the password itself to report a wrong one
+	rui, err := a.open(ctx, cmd != "unlock")
+	if err != nil {
+		return err
+	}
+	defer rui.Close()
+
+	return handler(ctx, rui, args)
+}
+
+// open opens the module, unlocking it with RUI3_PASSWORD when unlock is
+// set and the module is locked.
+func (a *app) open(ctx context.Context, unlock bool) (*rui3.RUI3, error) {
+	if a.portName == "" {
+		modules, err := rui3.Discover(ctx, rui3.WithBaudRate(a.baud))
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover modules: %w", err)
+		}
+		if len(modules) == 0 {
+			return nil, errors.New("no modules found, use --port")
+		}
+		a.portName = modules[0].Port
+	}
+
+	opts := []rui3.Option{rui3.WithBaudRate(a.baud)}
+	if password := os.Getenv("RUI3_PASSWORD"); password != "" && unlock {
+		opts = append(opts, rui3.WithPassword(rui3.Secret(password)))
+	}
+
+	return rui3.New(a.portName, opts...)
+}
+
+// print writes v as JSON with --json, otherwise it runs text.
+func (a *app) print(v any, text func()) error {
+	if !a.json {
+		text()
+		return nil
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func subcommand(name string, args []string, define func(*flag.FlagSet)) (*flag.FlagSet, error) {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	if define != nil {
+		define(flags)
+	}
+	err := flags.Parse(args)
+	return flags, err
+}
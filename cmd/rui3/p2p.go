@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"tencorvids/rui3-go"
+	"time"
+)
+
+func (a *app) p2p(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: rui3 p2p send|recv")
+	}
+
+	switch args[0] {
+	case "send":
+		return a.p2pSend(ctx, rui, args[1:])
+	case "recv":
+		return a.p2pRecv(ctx, rui, args[1:])
+	}
+
+	return fmt.Errorf("unknown p2p command %q", args[0])
+}
+
+// ensureP2P only switches a module to P2P when asked to, the switch
+// restarts it and lasts until it is switched back.
+func ensureP2P(ctx context.Context, rui *rui3.RUI3, switchMode bool) error {
+	mode, err := rui.GetNetworkMode()
+	if err != nil {
+		return err
+	}
+	if mode == rui3.NetworkModeP2P {
+		return nil
+	}
+	if !switchMode {
+		return fmt.Errorf("module is in %s mode, pass --switch-mode to restart it in P2P mode", mode)
+	}
+
+	_, err = rui.SwitchNetworkMode(ctx, rui3.NetworkModeP2P)
+	return err
+}
+
+func (a *app) p2pSend(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	var hexPayload string
+	var switchMode bool
+	_, err := subcommand("p2p send", args, func(f *flag.FlagSet) {
+		f.StringVar(&hexPayload, "hex", "", "payload as hex")
+		f.BoolVar(&switchMode, "switch-mode", false, "switch a LoRaWAN module to P2P, it stays in P2P")
+	})
+	if err != nil {
+		return err
+	}
+
+	payload, err := hex.DecodeString(hexPayload)
+	if err != nil || len(payload) == 0 {
+		return errors.New("invalid payload, pass --hex")
+	}
+
+	err = ensureP2P(ctx, rui, switchMode)
+	if err != nil {
+		return err
+	}
+
+	return rui.SendP2P(payload)
+}
+
+func (a *app) p2pRecv(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	var window time.Duration
+	var switchMode bool
+	_, err := subcommand("p2p recv", args, func(f *flag.FlagSet) {
+		f.DurationVar(&window, "window", 60*time.Second, "receive window, at most 65s")
+		f.BoolVar(&switchMode, "switch-mode", false, "switch a LoRaWAN module to P2P, it stays in P2P")
+	})
+	if err != nil {
+		return err
+	}
+
+	err = ensureP2P(ctx, rui, switchMode)
+	if err != nil {
+		return err
+	}
+
+	packet, err := rui.ReceiveP2P(ctx, window)
+	if err != nil {
+		return err
+	}
+
+	return a.print(packet, func() {
+		fmt.Printf("rssi=%d snr=%d payload=%X\n", packet.RSSI, packet.SNR, packet.Payload)
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"tencorvids/rui3-go"
+	"tencorvids/rui3-go/provision"
+)
+
+func (a *app) provision(ctx context.Context, rui *rui3.RUI3, args []string) error {
+	var joinEUI, out, keyStore string
+	var randomJoinEUI, reveal bool
+	_, err := subcommand("provision", args, func(f *flag.FlagSet) {
+		f.StringVar(&joinEUI, "joineui", "", "JoinEUI for every module as 16 hex digits")
+		f.BoolVar(&randomJoinEUI, "random-joineui", false, "generate a JoinEUI per module")
+		f.StringVar(&out, "out", "", "append the record to a .csv or .jsonl file")
+		f.StringVar(&keyStore, "keystore", "", "also save the keys to an encrypted key store, the passphrase is read from RUI3_KEYSTORE_PASSPHRASE")
+		f.BoolVar(&reveal, "reveal", false, "print the AppKey and write it to --out in plain text")
+	})
+	if err != nil {
+		return err
+	}
+	// the AppKey only exists on the module and in the record, without a key
+	// store or the plain key it could never be registered
+	if keyStore == "" && !reveal {
+		return errors.New("the AppKey would be lost, use --keystore or --reveal")
+	}
+
+	var opts []provision.Option
+	if joinEUI != "" {
+		opts = append(opts, provision.WithJoinEUI(joinEUI))
+	}
+	if randomJoinEUI {
+		opts = append(opts, provision.WithRandomJoinEUI())
+	}
+
+	if keyStore != "" {
+		store, err := openKeyStore(keyStore)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, provision.WithKeyStore(store))
+	}
+
+	var sinkOpts []provision.SinkOption
+	if reveal {
+		sinkOpts = append(sinkOpts, provision.RevealKeys())
+	}
+
+	var sink provision.Sink = provision.NewJSONSink(io.Discard)
+	if out != "" {
+		file, err := provision.OpenFile(out, sinkOpts...)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		sink = file
+	}
+
+	rec, err := provision.New(sink, opts...).Provision(ctx, rui)
+	if err != nil {
+		return err
+	}
+
+	if a.json && reveal {
+		return provision.NewJSONSink(os.Stdout, sinkOpts...).Write(rec)
+	}
+	return a.print(rec, func() {
+		appKey := rec.AppKey.String()
+		if reveal {
+			appKey = rec.AppKey.Reveal()
+		}
+		fmt.Printf("DevEUI:  %s\n", rec.DevEUI)
+		fmt.Printf("JoinEUI: %s\n", rec.JoinEUI)
+		fmt.Printf("AppKey:  %s\n", appKey)
+		fmt.Printf("Band:    %s\n", rec.Band)
+	})
+}
+
+// openKeyStore opens an encrypted key store with the passphrase from
+// RUI3_KEYSTORE_PASSPHRASE.
+func openKeyStore(path string) (rui3.KeyStore, error) {
+	passphrase := os.Getenv("RUI3_KEYSTORE_PASSPHRASE")
+	if passphrase == "" {
+		return nil, errors.New("RUI3_KEYSTORE_PASSPHRASE is not set")
+	}
+	return rui3.NewEncryptedFileKeyStore(path, rui3.Secret(passphrase)), nil
+}
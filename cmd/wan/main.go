@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"log/slog"
 	"os"
 	"tencorvids/rui3-go"
@@ -8,10 +10,25 @@ import (
 )
 
 func main() {
-	portName := "/dev/ttyS0"
-	slog.Info("Using port", "port", portName)
+	portName := flag.String("port", "", "serial port, discovered automatically when empty")
+	flag.Parse()
 
-	rui, err := rui3.New(portName)
+	if *portName == "" {
+		slog.Info("Discovering modules...")
+		modules, err := rui3.Discover(context.Background())
+		if err != nil {
+			slog.Error("Failed to discover modules", "error", err)
+			os.Exit(1)
+		}
+		if len(modules) == 0 {
+			slog.Error("No modules found")
+			os.Exit(1)
+		}
+		*portName = modules[0].Port
+	}
+	slog.Info("Using port", "port", *portName)
+
+	rui, err := rui3.New(*portName)
 	if err != nil {
 		slog.Error("Failed to create RUI3 instance", "error", err)
 		os.Exit(1)
@@ -19,12 +36,12 @@ func main() {
 	defer rui.Close()
 
 	slog.Info("Resetting chip, this will take a up to 15 seconds...")
-	err = rui.Reset()
+	banner, err := rui.Reset(context.Background())
 	if err != nil {
 		slog.Error("Failed to reset", "error", err)
 		os.Exit(1)
 	}
-	slog.Info("Chip reset, resuming...")
+	slog.Info("Chip reset, resuming...", "banner", banner)
 
 	attention, err := rui.Attention()
 	if err != nil {
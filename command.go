@@ -0,0 +1,195 @@
+package rui3
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:generate go run ./internal/atgen -spec commands.json
+
+const defaultTimeout = 5 * time.Second
+
+// Parser converts the value of a query response, the part after "AT+CMD=",
+// into a typed value.
+type Parser[T any] func(value string) (T, error)
+
+// Query sends "cmd=?" and parses the value of the matching response line.
+// Without a deadline on ctx the default 5 second timeout applies.
+func Query[T any](ctx context.Context, r *RUI3, cmd string, parse Parser[T]) (T, error) {
+	var zero T
+
+	response, err := r.Exec(ctx, cmd+"=?")
+	if err != nil {
+		return zero, err
+	}
+
+	prefix := cmd + "="
+	lines := strings.SplitSeq(response, "\n")
+	for line := range lines {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, prefix) {
+			raw := strings.TrimSpace(strings.TrimPrefix(line, prefix))
+			value, err := parse(raw)
+			if err != nil {
+				return zero, fmt.Errorf("invalid %s value %q: %w", cmd, raw, err)
+			}
+			return value, nil
+		}
+	}
+
+	return zero, fmt.Errorf("%s not found in response: %s", strings.TrimPrefix(cmd, "AT+"), response)
+}
+
+// Set sends "cmd=v1:v2:..." and waits for OK. Booleans are sent as 1/0.
+func (r *RUI3) Set(ctx context.Context, cmd string, values ...any) error {
+	params := make([]string, len(values))
+	for i, value := range values {
+		params[i] = formatValue(value)
+	}
+
+	response, err := r.Exec(ctx, cmd+"="+strings.Join(params, ":"))
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(response, "OK") {
+		return nil
+	}
+
+	return fmt.Errorf("failed to set %s: %s", strings.TrimPrefix(cmd, "AT+"), response)
+}
+
+// Exec sends a raw command and returns the full response.
+func (r *RUI3) Exec(ctx context.Context, cmd string) (string, error) {
+	name := commandName(cmd)
+	if r.locked && name != "AT+PWORD" {
+		return "", fmt.Errorf("%s: %w", name, ErrLocked)
+	}
+	if err := r.checkFirmware(name); err != nil {
+		return "", err
+	}
+
+	err := r.SendRawCommand(cmd)
+	if err != nil {
+		return "", fmt.Errorf("failed to send %s command: %w", name, err)
+	}
+
+	recvCtx, cancel := withDefaultTimeout(ctx, defaultTimeout)
+	defer cancel()
+
+	start := time.Now()
+	response, err := r.recvResponse(recvCtx)
+	r.logger.Debug("command", "cmd", name, "latency", time.Since(start), "error", err)
+	if err != nil {
+		return response, fmt.Errorf("failed to receive %s response: %w", name, err)
+	}
+
+	// the restart follows the OK, and a setting that keeps the current
+	// mode restarts nothing, which waitReady finds out by polling AT
+	if restarts(cmd) && !isBootBanner(response) {
+		_, err = r.waitReady(ctx)
+		if err != nil {
+			return response, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return response, nil
+}
+
+func withDefaultTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+func commandName(cmd string) string {
+	if i := strings.IndexByte(cmd, '='); i != -1 {
+		return cmd[:i]
+	}
+	return cmd
+}
+
+func firstField(value string) string {
+	if i := strings.IndexByte(value, ':'); i != -1 {
+		return value[:i]
+	}
+	return value
+}
+
+func validateHex(value string, length int) error {
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return err
+	}
+	if len(b) != length {
+		return fmt.Errorf("expected %d bytes, got %d", length, len(b))
+	}
+	return nil
+}
+
+func formatValue(value any) string {
+	switch v := value.(type) {
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case []byte:
+		return strings.ToUpper(hex.EncodeToString(v))
+	case Secret:
+		return v.Reveal()
+	}
+	return fmt.Sprint(value)
+}
+
+func ParseString(value string) (string, error) {
+	return value, nil
+}
+
+func ParseBool(value string) (bool, error) {
+	switch value {
+	case "1":
+		return true, nil
+	case "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("not a boolean")
+}
+
+func ParseInt(value string) (int, error) {
+	return strconv.Atoi(value)
+}
+
+func ParseUint32(value string) (uint32, error) {
+	v, err := strconv.ParseUint(value, 10, 32)
+	return uint32(v), err
+}
+
+func ParseFloat(value string) (float64, error) {
+	return strconv.ParseFloat(value, 64)
+}
+
+func ParseHex(value string) ([]byte, error) {
+	return hex.DecodeString(value)
+}
+
+func ParseTuple(value string) ([]string, error) {
+	return strings.Split(value, ":"), nil
+}
+
+// ParseEnum maps the first colon separated field of a value onto a set of
+// known values, e.g. "A" or "A:OK" for AT+CLASS.
+func ParseEnum[T any](values map[string]T) Parser[T] {
+	return func(value string) (T, error) {
+		if v, ok := values[firstField(value)]; ok {
+			return v, nil
+		}
+		var zero T
+		return zero, fmt.Errorf("unknown value")
+	}
+}
@@ -0,0 +1,384 @@
+// Code generated by atgen from commands.json. DO NOT EDIT.
+
+package rui3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// KnownCommands lists the commands described in commands.json.
+var KnownCommands = []string{
+	"AT",
+	"ATE",
+	"ATZ",
+	"ATR",
+	"AT+JOIN",
+	"AT+SEND",
+	"AT+PSEND",
+	"AT+PRECV",
+	"AT+PWORD",
+	"AT+LOCK",
+	"AT+BOOT",
+	"AT+SN",
+	"AT+VER",
+	"AT+APIVER",
+	"AT+CLIVER",
+	"AT+HWMODEL",
+	"AT+HWID",
+	"AT+BOOTVER",
+	"AT+UID",
+	"AT+ALIAS",
+	"AT+BUILDTIME",
+	"AT+REPOINFO",
+	"AT+BAT",
+	"AT+LPM",
+	"AT+LPMLVL",
+	"AT+BAUD",
+	"AT+SLEEP",
+	"AT+DEVEUI",
+	"AT+APPEUI",
+	"AT+APPKEY",
+	"AT+NJS",
+	"AT+CFM",
+	"AT+CLASS",
+	"AT+ADR",
+	"AT+MASK",
+	"AT+BAND",
+	"AT+UPCNT",
+	"AT+DOWNCNT",
+	"AT+DR",
+	"AT+TXP",
+	"AT+RETY",
+	"AT+RX1DL",
+	"AT+RX2DL",
+	"AT+JN1DL",
+	"AT+JN2DL",
+	"AT+RX2DR",
+	"AT+RX2FQ",
+	"AT+PNM",
+	"AT+NJM",
+	"AT+NWM",
+	"AT+DEVADDR",
+	"AT+APPSKEY",
+	"AT+NWKSKEY",
+	"AT+LINKCHECK",
+}
+
+// minVersions holds the first firmware release that supports a command.
+var minVersions = map[string]string{
+	"AT+LPMLVL": "4.0.0",
+}
+
+// secretCommands carry keys, their values are redacted in logs.
+var secretCommands = []string{
+	"AT+PWORD",
+	"AT+APPKEY",
+	"AT+APPSKEY",
+	"AT+NWKSKEY",
+}
+
+// restartCommands restart the module when they are executed or set.
+var restartCommands = map[string]bool{
+	"ATZ":    true,
+	"ATR":    true,
+	"AT+NWM": true,
+}
+
+type JoinMode int
+
+const (
+	JoinModeABP  JoinMode = 0
+	JoinModeOTAA JoinMode = 1
+)
+
+func parseJoinMode(value string) (JoinMode, error) {
+	return ParseEnum(map[string]JoinMode{
+		"0": JoinModeABP,
+		"1": JoinModeOTAA,
+	})(value)
+}
+
+func (v JoinMode) String() string {
+	switch v {
+	case JoinModeABP:
+		return "ABP"
+	case JoinModeOTAA:
+		return "OTAA"
+	}
+	return fmt.Sprintf("JoinMode(%d)", int(v))
+}
+
+func (v JoinMode) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *JoinMode) UnmarshalText(text []byte) error {
+	switch {
+	case strings.EqualFold(string(text), "ABP"):
+		*v = JoinModeABP
+	case strings.EqualFold(string(text), "OTAA"):
+		*v = JoinModeOTAA
+	default:
+		return fmt.Errorf("unknown JoinMode %q", text)
+	}
+	return nil
+}
+
+type NetworkMode int
+
+const (
+	NetworkModeP2P     NetworkMode = 0
+	NetworkModeLoRaWAN NetworkMode = 1
+	NetworkModeFSK     NetworkMode = 2
+)
+
+func parseNetworkMode(value string) (NetworkMode, error) {
+	return ParseEnum(map[string]NetworkMode{
+		"0": NetworkModeP2P,
+		"1": NetworkModeLoRaWAN,
+		"2": NetworkModeFSK,
+	})(value)
+}
+
+func (v NetworkMode) String() string {
+	switch v {
+	case NetworkModeP2P:
+		return "P2P"
+	case NetworkModeLoRaWAN:
+		return "LoRaWAN"
+	case NetworkModeFSK:
+		return "FSK"
+	}
+	return fmt.Sprintf("NetworkMode(%d)", int(v))
+}
+
+func (v NetworkMode) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *NetworkMode) UnmarshalText(text []byte) error {
+	switch {
+	case strings.EqualFold(string(text), "P2P"):
+		*v = NetworkModeP2P
+	case strings.EqualFold(string(text), "LoRaWAN"):
+		*v = NetworkModeLoRaWAN
+	case strings.EqualFold(string(text), "FSK"):
+		*v = NetworkModeFSK
+	default:
+		return fmt.Errorf("unknown NetworkMode %q", text)
+	}
+	return nil
+}
+
+// GetDataRate reads AT+DR: Uplink data rate.
+func (r *RUI3) GetDataRate() (int, error) {
+	return Query(context.Background(), r, "AT+DR", ParseInt)
+}
+
+// SetDataRate writes AT+DR: Uplink data rate.
+func (r *RUI3) SetDataRate(value int) error {
+	if value < 0 || value > 15 {
+		return fmt.Errorf("invalid data rate: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+DR", value)
+}
+
+// GetTxPower reads AT+TXP: Transmit power index.
+func (r *RUI3) GetTxPower() (int, error) {
+	return Query(context.Background(), r, "AT+TXP", ParseInt)
+}
+
+// SetTxPower writes AT+TXP: Transmit power index.
+func (r *RUI3) SetTxPower(value int) error {
+	if value < 0 || value > 15 {
+		return fmt.Errorf("invalid tx power: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+TXP", value)
+}
+
+// GetRetries reads AT+RETY: Confirmed uplink retransmissions.
+func (r *RUI3) GetRetries() (int, error) {
+	return Query(context.Background(), r, "AT+RETY", ParseInt)
+}
+
+// SetRetries writes AT+RETY: Confirmed uplink retransmissions.
+func (r *RUI3) SetRetries(value int) error {
+	if value < 0 || value > 7 {
+		return fmt.Errorf("invalid retries: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+RETY", value)
+}
+
+// GetReceiveDelay1 reads AT+RX1DL: RX1 window delay in seconds.
+func (r *RUI3) GetReceiveDelay1() (int, error) {
+	return Query(context.Background(), r, "AT+RX1DL", ParseInt)
+}
+
+// SetReceiveDelay1 writes AT+RX1DL: RX1 window delay in seconds.
+func (r *RUI3) SetReceiveDelay1(value int) error {
+	if value < 1 || value > 15 {
+		return fmt.Errorf("invalid receive delay 1: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+RX1DL", value)
+}
+
+// GetReceiveDelay2 reads AT+RX2DL: RX2 window delay in seconds.
+func (r *RUI3) GetReceiveDelay2() (int, error) {
+	return Query(context.Background(), r, "AT+RX2DL", ParseInt)
+}
+
+// SetReceiveDelay2 writes AT+RX2DL: RX2 window delay in seconds.
+func (r *RUI3) SetReceiveDelay2(value int) error {
+	if value < 2 || value > 16 {
+		return fmt.Errorf("invalid receive delay 2: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+RX2DL", value)
+}
+
+// GetJoinAcceptDelay1 reads AT+JN1DL: Join accept RX1 delay in seconds.
+func (r *RUI3) GetJoinAcceptDelay1() (int, error) {
+	return Query(context.Background(), r, "AT+JN1DL", ParseInt)
+}
+
+// SetJoinAcceptDelay1 writes AT+JN1DL: Join accept RX1 delay in seconds.
+func (r *RUI3) SetJoinAcceptDelay1(value int) error {
+	if value < 1 || value > 14 {
+		return fmt.Errorf("invalid join accept delay 1: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+JN1DL", value)
+}
+
+// GetJoinAcceptDelay2 reads AT+JN2DL: Join accept RX2 delay in seconds.
+func (r *RUI3) GetJoinAcceptDelay2() (int, error) {
+	return Query(context.Background(), r, "AT+JN2DL", ParseInt)
+}
+
+// SetJoinAcceptDelay2 writes AT+JN2DL: Join accept RX2 delay in seconds.
+func (r *RUI3) SetJoinAcceptDelay2(value int) error {
+	if value < 2 || value > 15 {
+		return fmt.Errorf("invalid join accept delay 2: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+JN2DL", value)
+}
+
+// GetRX2DataRate reads AT+RX2DR: RX2 window data rate.
+func (r *RUI3) GetRX2DataRate() (int, error) {
+	return Query(context.Background(), r, "AT+RX2DR", ParseInt)
+}
+
+// SetRX2DataRate writes AT+RX2DR: RX2 window data rate.
+func (r *RUI3) SetRX2DataRate(value int) error {
+	if value < 0 || value > 15 {
+		return fmt.Errorf("invalid rx2 data rate: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+RX2DR", value)
+}
+
+// GetRX2Frequency reads AT+RX2FQ: RX2 window frequency in Hz.
+func (r *RUI3) GetRX2Frequency() (int, error) {
+	return Query(context.Background(), r, "AT+RX2FQ", ParseInt)
+}
+
+// GetPublicNetworkMode reads AT+PNM: Public network mode.
+func (r *RUI3) GetPublicNetworkMode() (bool, error) {
+	return Query(context.Background(), r, "AT+PNM", ParseBool)
+}
+
+// SetPublicNetworkMode writes AT+PNM: Public network mode.
+func (r *RUI3) SetPublicNetworkMode(value bool) error {
+	return r.Set(context.Background(), "AT+PNM", value)
+}
+
+// GetJoinMode reads AT+NJM: Network join mode.
+func (r *RUI3) GetJoinMode() (JoinMode, error) {
+	return Query(context.Background(), r, "AT+NJM", parseJoinMode)
+}
+
+// SetJoinMode writes AT+NJM: Network join mode.
+func (r *RUI3) SetJoinMode(value JoinMode) error {
+	if _, err := parseJoinMode(fmt.Sprint(int(value))); err != nil {
+		return fmt.Errorf("invalid join mode: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+NJM", int(value))
+}
+
+// GetNetworkMode reads AT+NWM: Network work mode.
+func (r *RUI3) GetNetworkMode() (NetworkMode, error) {
+	return Query(context.Background(), r, "AT+NWM", parseNetworkMode)
+}
+
+// SetNetworkMode writes AT+NWM: Network work mode.
+func (r *RUI3) SetNetworkMode(value NetworkMode) error {
+	if _, err := parseNetworkMode(fmt.Sprint(int(value))); err != nil {
+		return fmt.Errorf("invalid network mode: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+NWM", int(value))
+}
+
+// GetDeviceAddress reads AT+DEVADDR: Device address.
+func (r *RUI3) GetDeviceAddress() (string, error) {
+	return Query(context.Background(), r, "AT+DEVADDR", ParseString)
+}
+
+// SetDeviceAddress writes AT+DEVADDR: Device address.
+func (r *RUI3) SetDeviceAddress(value string) error {
+	if err := validateHex(value, 4); err != nil {
+		return fmt.Errorf("invalid device address: %w", err)
+	}
+
+	return r.Set(context.Background(), "AT+DEVADDR", value)
+}
+
+// GetAppSessionKey reads AT+APPSKEY: Application session key.
+func (r *RUI3) GetAppSessionKey() (Secret, error) {
+	return Query(context.Background(), r, "AT+APPSKEY", ParseSecret)
+}
+
+// SetAppSessionKey writes AT+APPSKEY: Application session key.
+func (r *RUI3) SetAppSessionKey(value Secret) error {
+	if err := validateHex(value.Reveal(), 16); err != nil {
+		return fmt.Errorf("invalid app session key: %w", err)
+	}
+
+	return r.Set(context.Background(), "AT+APPSKEY", value)
+}
+
+// GetNetworkSessionKey reads AT+NWKSKEY: Network session key.
+func (r *RUI3) GetNetworkSessionKey() (Secret, error) {
+	return Query(context.Background(), r, "AT+NWKSKEY", ParseSecret)
+}
+
+// SetNetworkSessionKey writes AT+NWKSKEY: Network session key.
+func (r *RUI3) SetNetworkSessionKey(value Secret) error {
+	if err := validateHex(value.Reveal(), 16); err != nil {
+		return fmt.Errorf("invalid network session key: %w", err)
+	}
+
+	return r.Set(context.Background(), "AT+NWKSKEY", value)
+}
+
+// GetLinkCheck reads AT+LINKCHECK: Link check mode.
+func (r *RUI3) GetLinkCheck() (int, error) {
+	return Query(context.Background(), r, "AT+LINKCHECK", ParseInt)
+}
+
+// SetLinkCheck writes AT+LINKCHECK: Link check mode.
+func (r *RUI3) SetLinkCheck(value int) error {
+	if value < 0 || value > 2 {
+		return fmt.Errorf("invalid link check: %d", value)
+	}
+
+	return r.Set(context.Background(), "AT+LINKCHECK", value)
+}
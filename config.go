@@ -0,0 +1,303 @@
+package rui3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is a declarative LoRaWAN profile. Nil fields are left untouched
+// by ApplyConfig.
+type Config struct {
+	Band        *RegionBand `json:"band,omitempty" yaml:"band,omitempty"`
+	ChannelMask *string     `json:"channelMask,omitempty" yaml:"channelMask,omitempty"`
+	Class       *Class      `json:"class,omitempty" yaml:"class,omitempty"`
+	JoinMode    *JoinMode   `json:"joinMode,omitempty" yaml:"joinMode,omitempty"`
+	ADR         *bool       `json:"adr,omitempty" yaml:"adr,omitempty"`
+	DataRate    *int        `json:"dataRate,omitempty" yaml:"dataRate,omitempty"`
+	TxPower     *int        `json:"txPower,omitempty" yaml:"txPower,omitempty"`
+	ConfirmMode *bool       `json:"confirmMode,omitempty" yaml:"confirmMode,omitempty"`
+	Retries     *int        `json:"retries,omitempty" yaml:"retries,omitempty"`
+
+	Keys      ConfigKeys      `json:"keys,omitzero" yaml:"keys,omitempty"`
+	RXWindows ConfigRXWindows `json:"rxWindows,omitzero" yaml:"rxWindows,omitempty"`
+}
+
+// ConfigKeys holds the OTAA keys and the ABP session. Keys are hex strings.
+type ConfigKeys struct {
+	DevEUI  *string `json:"devEUI,omitempty" yaml:"devEUI,omitempty"`
+	AppEUI  *string `json:"appEUI,omitempty" yaml:"appEUI,omitempty"`
+	AppKey  *Secret `json:"appKey,omitempty" yaml:"appKey,omitempty"`
+	DevAddr *string `json:"devAddr,omitempty" yaml:"devAddr,omitempty"`
+	AppSKey *Secret `json:"appSKey,omitempty" yaml:"appSKey,omitempty"`
+	NwkSKey *Secret `json:"nwkSKey,omitempty" yaml:"nwkSKey,omitempty"`
+}
+
+// ConfigRXWindows holds the receive window delays in seconds and the RX2
+// data rate.
+type ConfigRXWindows struct {
+	RX1Delay         *int `json:"rx1Delay,omitempty" yaml:"rx1Delay,omitempty"`
+	RX2Delay         *int `json:"rx2Delay,omitempty" yaml:"rx2Delay,omitempty"`
+	RX2DataRate      *int `json:"rx2DataRate,omitempty" yaml:"rx2DataRate,omitempty"`
+	JoinAcceptDelay1 *int `json:"joinAcceptDelay1,omitempty" yaml:"joinAcceptDelay1,omitempty"`
+	JoinAcceptDelay2 *int `json:"joinAcceptDelay2,omitempty" yaml:"joinAcceptDelay2,omitempty"`
+}
+
+// Change is a single setting ApplyConfig writes. Keys are redacted.
+type Change struct {
+	Field   string `json:"field"`
+	Command string `json:"command"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+func (c Change) String() string {
+	from := c.From
+	if from == "" {
+		from = "unknown"
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Field, from, c.To)
+}
+
+type configField struct {
+	name   string
+	cmd    string
+	read   func(ctx context.Context, r *RUI3, c *Config) error
+	differ func(current, desired *Config) bool
+	arg    func(c *Config) any
+	text   func(c *Config) string
+}
+
+func field[T comparable](name, cmd string, ptr func(*Config) **T, parse Parser[T], arg func(T) any) configField {
+	return configField{
+		name: name,
+		cmd:  cmd,
+		read: func(ctx context.Context, r *RUI3, c *Config) error {
+			if !r.Supports(cmd) {
+				return nil
+			}
+			value, err := Query(ctx, r, cmd, parse)
+			if errors.Is(err, ErrCommandNotFound) || errors.Is(err, ErrUnsupportedFirmware) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			*ptr(c) = &value
+			return nil
+		},
+		differ: func(current, desired *Config) bool {
+			want := *ptr(desired)
+			if want == nil {
+				return false
+			}
+			have := *ptr(current)
+			return have == nil || *have != *want
+		},
+		arg: func(c *Config) any {
+			return arg(**ptr(c))
+		},
+		text: func(c *Config) string {
+			value := *ptr(c)
+			if value == nil {
+				return ""
+			}
+			return fmt.Sprint(*value)
+		},
+	}
+}
+
+func asIs[T any](v T) any { return v }
+
+func parseMaskHex(value string) (string, error) {
+	return strings.ToUpper(firstField(value)), nil
+}
+
+// configFields lists the settings in the order they are applied. The band
+// comes first since changing it resets the regional parameters.
+var configFields = []configField{
+	field("band", "AT+BAND", func(c *Config) **RegionBand { return &c.Band }, parseRegionBand,
+		func(v RegionBand) any { return int(v) }),
+	// the mask is kept as hex, ChannelMask can not represent several
+	// sub-bands
+	field("channelMask", "AT+MASK", func(c *Config) **string { return &c.ChannelMask }, parseMaskHex, asIs[string]),
+	field("class", "AT+CLASS", func(c *Config) **Class { return &c.Class }, ParseEnum(classValues),
+		func(v Class) any { return v.String() }),
+	field("joinMode", "AT+NJM", func(c *Config) **JoinMode { return &c.JoinMode }, parseJoinMode,
+		func(v JoinMode) any { return int(v) }),
+	field("keys.devEUI", "AT+DEVEUI", func(c *Config) **string { return &c.Keys.DevEUI }, ParseString, asIs[string]),
+	field("keys.appEUI", "AT+APPEUI", func(c *Config) **string { return &c.Keys.AppEUI }, ParseString, asIs[string]),
+	field("keys.appKey", "AT+APPKEY", func(c *Config) **Secret { return &c.Keys.AppKey }, ParseSecret, asIs[Secret]),
+	field("keys.devAddr", "AT+DEVADDR", func(c *Config) **string { return &c.Keys.DevAddr }, ParseString, asIs[string]),
+	field("keys.appSKey", "AT+APPSKEY", func(c *Config) **Secret { return &c.Keys.AppSKey }, ParseSecret, asIs[Secret]),
+	field("keys.nwkSKey", "AT+NWKSKEY", func(c *Config) **Secret { return &c.Keys.NwkSKey }, ParseSecret, asIs[Secret]),
+	// ADR goes before the data rate, the module ignores DR while ADR is on
+	field("adr", "AT+ADR", func(c *Config) **bool { return &c.ADR }, ParseBool, asIs[bool]),
+	field("dataRate", "AT+DR", func(c *Config) **int { return &c.DataRate }, ParseInt, asIs[int]),
+	field("txPower", "AT+TXP", func(c *Config) **int { return &c.TxPower }, ParseInt, asIs[int]),
+	field("confirmMode", "AT+CFM", func(c *Config) **bool { return &c.ConfirmMode }, ParseBool, asIs[bool]),
+	field("retries", "AT+RETY", func(c *Config) **int { return &c.Retries }, ParseInt, asIs[int]),
+	field("rxWindows.rx1Delay", "AT+RX1DL", func(c *Config) **int { return &c.RXWindows.RX1Delay }, ParseInt, asIs[int]),
+	field("rxWindows.rx2Delay", "AT+RX2DL", func(c *Config) **int { return &c.RXWindows.RX2Delay }, ParseInt, asIs[int]),
+	field("rxWindows.rx2DataRate", "AT+RX2DR", func(c *Config) **int { return &c.RXWindows.RX2DataRate }, ParseInt, asIs[int]),
+	field("rxWindows.joinAcceptDelay1", "AT+JN1DL", func(c *Config) **int { return &c.RXWindows.JoinAcceptDelay1 }, ParseInt, asIs[int]),
+	field("rxWindows.joinAcceptDelay2", "AT+JN2DL", func(c *Config) **int { return &c.RXWindows.JoinAcceptDelay2 }, ParseInt, asIs[int]),
+}
+
+// LoadConfig reads a profile from a .json, .yaml or .yml file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var cfg Config
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &cfg)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &cfg)
+	default:
+		return nil, fmt.Errorf("unknown config format %q", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Validate checks the ranges the setters enforce and upper-cases the keys
+// and the channel mask so they compare equal to what the module reports.
+func (c *Config) Validate() error {
+	if c.Band != nil && (*c.Band < EU433 || *c.Band > LA915) {
+		return fmt.Errorf("invalid region band: %d", *c.Band)
+	}
+	if c.Class != nil && (*c.Class < ClassA || *c.Class > ClassC) {
+		return fmt.Errorf("invalid class: %d", *c.Class)
+	}
+
+	ranges := []struct {
+		name     string
+		value    *int
+		min, max int
+	}{
+		{"data rate", c.DataRate, 0, 15},
+		{"tx power", c.TxPower, 0, 15},
+		{"retries", c.Retries, 0, 7},
+		{"receive delay1", c.RXWindows.RX1Delay, 1, 15},
+		{"receive delay2", c.RXWindows.RX2Delay, 2, 16},
+		{"rx2 data rate", c.RXWindows.RX2DataRate, 0, 15},
+		{"join accept delay1", c.RXWindows.JoinAcceptDelay1, 1, 14},
+		{"join accept delay2", c.RXWindows.JoinAcceptDelay2, 2, 15},
+	}
+	for _, r := range ranges {
+		if r.value != nil && (*r.value < r.min || *r.value > r.max) {
+			return fmt.Errorf("invalid %s: %d", r.name, *r.value)
+		}
+	}
+
+	hexFields := []struct {
+		name   string
+		value  *string
+		length int
+	}{
+		{"channel mask", c.ChannelMask, 2},
+		{"DevEUI", c.Keys.DevEUI, 8},
+		{"AppEUI", c.Keys.AppEUI, 8},
+		{"AppKey", (*string)(c.Keys.AppKey), 16},
+		{"DevAddr", c.Keys.DevAddr, 4},
+		{"AppSKey", (*string)(c.Keys.AppSKey), 16},
+		{"NwkSKey", (*string)(c.Keys.NwkSKey), 16},
+	}
+	for _, k := range hexFields {
+		if k.value == nil {
+			continue
+		}
+		if err := validateHex(*k.value, k.length); err != nil {
+			return fmt.Errorf("invalid %s: %w", k.name, err)
+		}
+		*k.value = strings.ToUpper(*k.value)
+	}
+
+	return nil
+}
+
+// ReadConfig reads every setting Config covers. Settings the firmware does
+// not know are left nil.
+func (r *RUI3) ReadConfig(ctx context.Context) (*Config, error) {
+	var cfg Config
+	for _, f := range configFields {
+		err := f.read(ctx, r, &cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.name, err)
+		}
+	}
+	return &cfg, nil
+}
+
+// DiffConfig lists the changes needed to bring current to desired, in the
+// order they have to be applied.
+func DiffConfig(current, desired *Config) []Change {
+	var changes []Change
+	for _, f := range configFields {
+		if f.differ(current, desired) {
+			changes = append(changes, f.change(current, desired))
+		}
+	}
+	return changes
+}
+
+func (f configField) change(current, desired *Config) Change {
+	return Change{Field: f.name, Command: f.cmd, From: f.text(current), To: f.text(desired)}
+}
+
+// ApplyConfig reads the current settings and writes only those that differ
+// from cfg. It returns the changes that were written, up to the one that
+// failed.
+func (r *RUI3) ApplyConfig(ctx context.Context, cfg *Config) ([]Change, error) {
+	err := cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := r.ReadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []Change
+	for _, f := range configFields {
+		if !f.differ(current, cfg) {
+			continue
+		}
+
+		change := f.change(current, cfg)
+		err := r.Set(ctx, f.cmd, f.arg(cfg))
+		if err != nil {
+			return applied, fmt.Errorf("failed to apply %s: %w", f.name, err)
+		}
+		applied = append(applied, change)
+
+		// a new band brings new regional defaults, diff the rest against them
+		if f.cmd == "AT+BAND" {
+			current, err = r.ReadConfig(ctx)
+			if err != nil {
+				return applied, err
+			}
+		}
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,219 @@
+// Package dfu updates the firmware of a RUI3 module over its AT port.
+//
+// AT+BOOT starts the UART bootloader, which speaks the legacy serial DFU
+// protocol of the Nordic nRF5 SDK 11 that RAK's update instructions drive
+// with adafruit-nrfutil ("adafruit-nrfutil dfu serial --package <zip>
+// --singlebank"). Packets are framed as HCI packets in SLIP with a CRC-16,
+// each acknowledged with the next expected sequence number. A start packet
+// announces the application size, the init packet carries the CRC-16 of the
+// image, 512 byte data packets follow and a stop packet makes the
+// bootloader check the image and start it. Framing and timing follow
+// nordicsemi/dfu/dfu_transport_serial.py in
+// https://github.com/adafruit/Adafruit_nRF52_nrfutil.
+package dfu
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"tencorvids/rui3-go"
+)
+
+// Flash timing of dfu_transport_serial.py. The bootloader does not
+// acknowledge the end of an erase or a page write, so the sender waits.
+const (
+	flashPageSize      = 4096
+	flashPageEraseTime = 89700 * time.Microsecond
+	flashPageWriteTime = 102400 * time.Microsecond
+	minEraseTime       = 500 * time.Millisecond
+	maxPacketSize      = 512
+)
+
+var ErrVerify = errors.New("firmware verification failed")
+
+type Progress struct {
+	Sent  int
+	Total int
+}
+
+type Option func(*options)
+
+type options struct {
+	chunkSize    int
+	retries      int
+	frameTimeout time.Duration
+	initPacket   []byte
+	progress     func(Progress)
+}
+
+// WithChunkSize sets the bytes per data packet, 512 by default, which is
+// also the most the bootloader accepts.
+func WithChunkSize(size int) Option {
+	return func(o *options) {
+		o.chunkSize = size
+	}
+}
+
+// WithRetries sets how often a packet is resent, 3 times by default.
+func WithRetries(retries int) Option {
+	return func(o *options) {
+		o.retries = retries
+	}
+}
+
+// WithFrameTimeout sets how long to wait for a packet to be acknowledged,
+// 1 second by default.
+func WithFrameTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.frameTimeout = timeout
+	}
+}
+
+// WithInitPacket sends the init packet of a DFU package, the .dat file,
+// instead of one generated for the image.
+func WithInitPacket(initPacket []byte) Option {
+	return func(o *options) {
+		o.initPacket = initPacket
+	}
+}
+
+// WithProgress is called after every acknowledged data packet.
+func WithProgress(progress func(Progress)) Option {
+	return func(o *options) {
+		o.progress = progress
+	}
+}
+
+// UpdateFile flashes a DFU package (.zip) as published by RAK, or a bare
+// application image (.bin) with a generated init packet.
+func UpdateFile(ctx context.Context, r *rui3.RUI3, path string, opts ...Option) error {
+	if strings.EqualFold(filepath.Ext(path), ".zip") {
+		image, initPacket, err := ReadPackage(path)
+		if err != nil {
+			return err
+		}
+		return Update(ctx, r, image, append([]Option{WithInitPacket(initPacket)}, opts...)...)
+	}
+
+	image, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+
+	return Update(ctx, r, image, opts...)
+}
+
+// Update enters the bootloader, transfers the image and waits for the new
+// application, whose version is then detected again. When a step fails,
+// or the bootloader rejects the image, the module is left in the
+// bootloader so the update can be run again.
+func Update(ctx context.Context, r *rui3.RUI3, image []byte, opts ...Option) error {
+	o := options{chunkSize: maxPacketSize, retries: 3, frameTimeout: time.Second}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(image) == 0 {
+		return errors.New("empty firmware image")
+	}
+	if o.chunkSize <= 0 || o.chunkSize > maxPacketSize {
+		return fmt.Errorf("invalid chunk size: %d", o.chunkSize)
+	}
+	if o.initPacket == nil {
+		o.initPacket = InitPacket(image)
+	}
+
+	// a failed earlier update left the module in the bootloader, which
+	// does not answer AT commands
+	err := r.SendRawCommand("AT+BOOT")
+	if err != nil {
+		return fmt.Errorf("failed to enter bootloader: %w", err)
+	}
+	r.RecvResponse(time.Second)
+
+	err = r.Raw(func(p *rui3.RawPort) error {
+		return transfer(ctx, &hciConn{port: p, o: o}, image, o)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.WaitReady(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: the new application did not start: %w", ErrVerify, err)
+	}
+
+	_, err = r.DetectFirmware(ctx)
+	return err
+}
+
+func transfer(ctx context.Context, c *hciConn, image []byte, o options) error {
+	start := binary.LittleEndian.AppendUint32(nil, dfuStartPacket)
+	start = binary.LittleEndian.AppendUint32(start, dfuUpdateModeApp)
+	// SoftDevice, bootloader and application size
+	start = binary.LittleEndian.AppendUint32(start, 0)
+	start = binary.LittleEndian.AppendUint32(start, 0)
+	start = binary.LittleEndian.AppendUint32(start, uint32(len(image)))
+	err := c.send(ctx, start)
+	if err != nil {
+		return fmt.Errorf("failed to start transfer: %w", err)
+	}
+	err = sleep(ctx, max(minEraseTime, time.Duration(len(image)/flashPageSize+1)*flashPageEraseTime))
+	if err != nil {
+		return err
+	}
+
+	initPacket := binary.LittleEndian.AppendUint32(nil, dfuInitPacket)
+	initPacket = append(initPacket, o.initPacket...)
+	initPacket = append(initPacket, 0, 0)
+	err = c.send(ctx, initPacket)
+	if err != nil {
+		return fmt.Errorf("failed to send init packet: %w", err)
+	}
+
+	for i, offset := 0, 0; offset < len(image); i, offset = i+1, offset+o.chunkSize {
+		chunk := image[offset:min(offset+o.chunkSize, len(image))]
+		err = c.send(ctx, append(binary.LittleEndian.AppendUint32(nil, dfuDataPacket), chunk...))
+		if err != nil {
+			return fmt.Errorf("failed to send data at offset %d: %w", offset, err)
+		}
+
+		if o.progress != nil {
+			o.progress(Progress{Sent: offset + len(chunk), Total: len(image)})
+		}
+		if i%8 == 0 {
+			err = sleep(ctx, flashPageWriteTime)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = sleep(ctx, flashPageWriteTime)
+	if err != nil {
+		return err
+	}
+
+	// the bootloader restarts into the new application once the image
+	// checks out
+	c.port.ExpectRestart()
+	err = c.send(ctx, binary.LittleEndian.AppendUint32(nil, dfuStopDataPacket))
+	if err != nil {
+		return fmt.Errorf("failed to stop transfer: %w", err)
+	}
+	return nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
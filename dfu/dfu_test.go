@@ -0,0 +1,123 @@
+package dfu_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"tencorvids/rui3-go"
+	"tencorvids/rui3-go/dfu"
+	"tencorvids/rui3-go/sim"
+)
+
+func testImage(size int) []byte {
+	image := make([]byte, size)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range image {
+		image[i] = byte(rng.Uint32())
+	}
+	// the SLIP delimiter and escape have to survive the framing
+	image[0], image[1] = 0xC0, 0xDB
+	return image
+}
+
+func open(t *testing.T) (*sim.Module, *rui3.RUI3) {
+	t.Helper()
+
+	m := sim.New()
+	r := rui3.NewWithPort(m)
+	t.Cleanup(func() { r.Close() })
+	return m, r
+}
+
+func TestUpdate(t *testing.T) {
+	m, r := open(t)
+	image := testImage(5000)
+
+	var last dfu.Progress
+	err := dfu.Update(context.Background(), r, image, dfu.WithProgress(func(p dfu.Progress) {
+		last = p
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(m.Firmware(), image) {
+		t.Errorf("installed %d bytes, want the %d byte image", len(m.Firmware()), len(image))
+	}
+	if last.Sent != len(image) || last.Total != len(image) {
+		t.Errorf("last progress = %+v, want %d of %d", last, len(image), len(image))
+	}
+	if _, err := r.GetFirmwareVersion(); err != nil {
+		t.Errorf("module not usable after the update: %v", err)
+	}
+}
+
+func TestUpdateResendsDamagedPackets(t *testing.T) {
+	m, r := open(t)
+	image := testImage(2000)
+	m.FailDFUWrites(2)
+
+	err := dfu.Update(context.Background(), r, image, dfu.WithChunkSize(256))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m.Firmware(), image) {
+		t.Error("image not installed")
+	}
+}
+
+func TestUpdateRejectedImage(t *testing.T) {
+	m, r := open(t)
+	image := testImage(1000)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	err := dfu.Update(ctx, r, image, dfu.WithInitPacket(dfu.InitPacket(testImage(999))))
+	if !errors.Is(err, dfu.ErrVerify) {
+		t.Errorf("Update = %v, want ErrVerify", err)
+	}
+	if m.Firmware() != nil {
+		t.Error("rejected image was installed")
+	}
+}
+
+func TestUpdateFilePackage(t *testing.T) {
+	m, r := open(t)
+	image := testImage(3000)
+
+	path := filepath.Join(t.TempDir(), "RAK3172-E_latest_final.zip")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	files := map[string][]byte{
+		"manifest.json": []byte(`{"manifest": {"application": {"bin_file": "app.bin", "dat_file": "app.dat"}, "dfu_version": 0.5}}`),
+		"app.bin":       image,
+		"app.dat":       dfu.InitPacket(image),
+	}
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	zw.Close()
+	f.Close()
+
+	err = dfu.UpdateFile(context.Background(), r, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m.Firmware(), image) {
+		t.Error("image not installed")
+	}
+}
@@ -0,0 +1,188 @@
+package dfu
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"tencorvids/rui3-go"
+)
+
+// SLIP framing, RFC 1055
+const (
+	slipEnd    = 0xC0
+	slipEsc    = 0xDB
+	slipEscEnd = 0xDC
+	slipEscEsc = 0xDD
+)
+
+// HCI packet header fields as sent by dfu_transport_serial.py
+const (
+	hciIntegrityCheck = 1
+	hciReliable       = 1
+	hciPacketType     = 14
+)
+
+// DFU packet types, the first 32-bit word of every HCI payload
+const (
+	dfuInitPacket     = 1
+	dfuStartPacket    = 3
+	dfuDataPacket     = 4
+	dfuStopDataPacket = 5
+
+	dfuUpdateModeApp = 4
+)
+
+// crc16 is CRC-16/CCITT-FALSE, crc16_compute of the nRF5 SDK.
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// hciHeader packs the four header bytes. The ack number is the sequence
+// number the sender expects next, the last byte makes the header sum zero.
+func hciHeader(seq, ack byte, integrity, reliable, packetType byte, length int) []byte {
+	h := []byte{
+		seq | ack<<3 | integrity<<6 | reliable<<7,
+		packetType | byte(length&0x0F)<<4,
+		byte(length >> 4),
+		0,
+	}
+	h[3] = -(h[0] + h[1] + h[2])
+	return h
+}
+
+// encodePacket builds the SLIP frame of a reliable HCI packet with its
+// CRC-16.
+func encodePacket(seq byte, payload []byte) []byte {
+	packet := hciHeader(seq, (seq+1)%8, hciIntegrityCheck, hciReliable, hciPacketType, len(payload))
+	packet = append(packet, payload...)
+	packet = binary.LittleEndian.AppendUint16(packet, crc16(packet))
+	return slipEncode(packet)
+}
+
+func slipEncode(data []byte) []byte {
+	frame := []byte{slipEnd}
+	for _, b := range data {
+		switch b {
+		case slipEnd:
+			frame = append(frame, slipEsc, slipEscEnd)
+		case slipEsc:
+			frame = append(frame, slipEsc, slipEscEsc)
+		default:
+			frame = append(frame, b)
+		}
+	}
+	return append(frame, slipEnd)
+}
+
+// hciConn sends HCI packets and waits for the bootloader's
+// acknowledgements.
+type hciConn struct {
+	port *rui3.RawPort
+	o    options
+	seq  byte
+	buf  []byte
+}
+
+// send transmits payload until the bootloader acknowledges it. A packet
+// that fails its CRC is acknowledged with the previous number and sent
+// again, as is one whose acknowledgement does not arrive in time.
+func (c *hciConn) send(ctx context.Context, payload []byte) error {
+	c.seq = (c.seq + 1) % 8
+	frame := encodePacket(c.seq, payload)
+
+	var err error
+	for attempt := 0; attempt <= c.o.retries; attempt++ {
+		_, err = c.port.Write(frame)
+		if err != nil {
+			return err
+		}
+
+		var ack byte
+		ack, err = c.readAck(ctx)
+		if err == nil && ack == (c.seq+1)%8 {
+			return nil
+		}
+		if err == nil {
+			err = fmt.Errorf("acknowledged %d instead of %d", ack, (c.seq+1)%8)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// readAck returns the ack number of the next SLIP frame.
+func (c *hciConn) readAck(ctx context.Context) (byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.o.frameTimeout)
+	defer cancel()
+
+	for {
+		frame, err := c.readFrame(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if len(frame) >= 4 && frame[0]+frame[1]+frame[2]+frame[3] == 0 {
+			return frame[0] >> 3 & 0x07, nil
+		}
+	}
+}
+
+// readFrame returns the decoded content of the next non-empty SLIP frame.
+func (c *hciConn) readFrame(ctx context.Context) ([]byte, error) {
+	var frame []byte
+	inFrame := false
+	escaped := false
+	chunk := make([]byte, 64)
+
+	for {
+		if len(c.buf) == 0 {
+			n, err := c.port.ReadContext(ctx, chunk)
+			if errors.Is(err, context.DeadlineExceeded) {
+				return nil, errors.New("no acknowledgement from bootloader")
+			}
+			if err != nil {
+				return nil, err
+			}
+			c.buf = chunk[:n]
+		}
+
+		b := c.buf[0]
+		c.buf = c.buf[1:]
+		switch {
+		case b == slipEnd:
+			if inFrame && len(frame) > 0 {
+				return frame, nil
+			}
+			inFrame = true
+			frame = frame[:0]
+		case !inFrame:
+			// bytes outside a frame, e.g. the rest of an AT response
+		case escaped:
+			escaped = false
+			switch b {
+			case slipEscEnd:
+				frame = append(frame, slipEnd)
+			case slipEscEsc:
+				frame = append(frame, slipEsc)
+			}
+		case b == slipEsc:
+			escaped = true
+		default:
+			frame = append(frame, b)
+		}
+	}
+}
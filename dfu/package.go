@@ -0,0 +1,83 @@
+package dfu
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Init packet values adafruit-nrfutil uses when none are given: any device
+// type and revision, any application version and any SoftDevice.
+const (
+	anyDeviceType   = 0xFFFF
+	anyDeviceRev    = 0xFFFF
+	anyAppVersion   = 0xFFFFFFFF
+	anySoftDevice   = 0xFFFE
+	softDeviceCount = 1
+)
+
+// InitPacket returns the init packet adafruit-nrfutil generates for a
+// package of image: device type, revision, application version, the
+// accepted SoftDevices and the CRC-16 of the image.
+func InitPacket(image []byte) []byte {
+	p := binary.LittleEndian.AppendUint16(nil, anyDeviceType)
+	p = binary.LittleEndian.AppendUint16(p, anyDeviceRev)
+	p = binary.LittleEndian.AppendUint32(p, anyAppVersion)
+	p = binary.LittleEndian.AppendUint16(p, softDeviceCount)
+	p = binary.LittleEndian.AppendUint16(p, anySoftDevice)
+	return binary.LittleEndian.AppendUint16(p, crc16(image))
+}
+
+type manifest struct {
+	Manifest struct {
+		Application *struct {
+			BinFile string `json:"bin_file"`
+			DatFile string `json:"dat_file"`
+		} `json:"application"`
+	} `json:"manifest"`
+}
+
+// ReadPackage reads the application image and its init packet from a DFU
+// package as written by "adafruit-nrfutil dfu genpkg".
+func ReadPackage(path string) (image, initPacket []byte, err error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open package: %w", err)
+	}
+	defer zr.Close()
+
+	read := func(name string) ([]byte, error) {
+		f, err := zr.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read package: %w", err)
+		}
+		defer f.Close()
+		return io.ReadAll(f)
+	}
+
+	data, err := read("manifest.json")
+	if err != nil {
+		return nil, nil, err
+	}
+	var m manifest
+	err = json.Unmarshal(data, &m)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	app := m.Manifest.Application
+	if app == nil {
+		return nil, nil, fmt.Errorf("package contains no application")
+	}
+
+	image, err = read(app.BinFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	initPacket, err = read(app.DatFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	return image, initPacket, nil
+}
@@ -0,0 +1,100 @@
+package rui3
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial/enumerator"
+)
+
+type DiscoveredModule struct {
+	Port          string
+	USB           bool
+	VID           string
+	PID           string
+	USBSerial     string
+	Product       string
+	HardwareModel string
+	SerialNumber  string
+	DevEUI        string
+}
+
+// Discover probes every serial port on the host with AT and AT+HWMODEL=?
+// and returns the ones that answer like a RUI3 module. Ports that cannot
+// be opened, e.g. because they are in use, are skipped.
+func Discover(ctx context.Context, opts ...Option) ([]DiscoveredModule, error) {
+	ports, err := enumerator.GetDetailedPortsList()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list serial ports: %w", err)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	modules := make([]DiscoveredModule, 0)
+
+	for _, port := range ports {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			module, ok := probePort(ctx, port, opts)
+			if !ok {
+				return
+			}
+
+			mu.Lock()
+			modules = append(modules, module)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return modules, ctx.Err()
+	}
+
+	slices.SortFunc(modules, func(a, b DiscoveredModule) int {
+		return strings.Compare(a.Port, b.Port)
+	})
+
+	return modules, nil
+}
+
+func probePort(ctx context.Context, port *enumerator.PortDetails, opts []Option) (DiscoveredModule, bool) {
+	module := DiscoveredModule{
+		Port:      port.Name,
+		USB:       port.IsUSB,
+		VID:       port.VID,
+		PID:       port.PID,
+		USBSerial: port.SerialNumber,
+		Product:   port.Product,
+	}
+
+	r, err := New(port.Name, opts...)
+	if err != nil {
+		return module, false
+	}
+	defer r.Close()
+
+	ctx, cancel := withDefaultTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if !r.probe(ctx) {
+		return module, false
+	}
+
+	module.HardwareModel, err = Query(ctx, r, "AT+HWMODEL", ParseString)
+	if err != nil {
+		return module, false
+	}
+
+	// identification is best effort, the module was found either way
+	module.SerialNumber, _ = Query(ctx, r, "AT+SN", ParseString)
+	module.DevEUI, _ = Query(ctx, r, "AT+DEVEUI", ParseString)
+
+	return module, true
+}
@@ -0,0 +1,64 @@
+package rui3
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrCommandNotFound   = errors.New("AT command not found")
+	ErrParamError        = errors.New("AT parameter error")
+	ErrSendConfirmFailed = errors.New("confirmed send failed")
+	ErrNoNetworkJoined   = errors.New("no network joined")
+	ErrLocked            = errors.New("AT interface locked")
+	ErrCommandFailed     = errors.New("AT command failed")
+
+	ErrUnsupportedFirmware = errors.New("command not supported by firmware")
+)
+
+type CommandError struct {
+	Line string
+	Err  error
+}
+
+func (e *CommandError) Error() string {
+	return "command error: " + e.Line
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+func parseCommandError(line string) error {
+	switch {
+	case strings.Contains(line, "AT_COMMAND_NOT_FOUND"):
+		return &CommandError{Line: line, Err: ErrCommandNotFound}
+	case strings.Contains(line, "AT_PARAM_ERROR"):
+		return &CommandError{Line: line, Err: ErrParamError}
+	case strings.Contains(line, "SEND_CONFIRMED_FAILED"):
+		return &CommandError{Line: line, Err: ErrSendConfirmFailed}
+	case strings.Contains(line, "AT_NO_NETWORK_JOINED"):
+		return &CommandError{Line: line, Err: ErrNoNetworkJoined}
+	// the generic error, e.g. for LoRaWAN commands in P2P mode
+	case line == "AT_ERROR":
+		return &CommandError{Line: line, Err: ErrCommandFailed}
+	}
+	return nil
+}
+
+// UnsupportedFirmwareError is returned, without sending anything, for a
+// command that needs a newer firmware than the one detected on connect.
+type UnsupportedFirmwareError struct {
+	Command  string
+	Required Version
+	Current  Version
+}
+
+func (e *UnsupportedFirmwareError) Error() string {
+	return fmt.Sprintf("%s requires firmware %s, module runs %s", e.Command, e.Required, e.Current)
+}
+
+func (e *UnsupportedFirmwareError) Unwrap() error {
+	return ErrUnsupportedFirmware
+}
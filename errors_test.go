@@ -0,0 +1,46 @@
+package rui3_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tencorvids/rui3-go"
+	"tencorvids/rui3-go/sim"
+)
+
+func TestCommandErrors(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  error
+	}{
+		{"AT_ERROR", rui3.ErrCommandFailed},
+		{"AT_PARAM_ERROR", rui3.ErrParamError},
+		{"AT_COMMAND_NOT_FOUND", rui3.ErrCommandNotFound},
+		{"AT_NO_NETWORK_JOINED", rui3.ErrNoNetworkJoined},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			m := sim.New()
+			m.Handle("AT+NJS", func(m *sim.Module, param string) []string {
+				return []string{tt.reply}
+			})
+			r := rui3.NewWithPort(m)
+			defer r.Close()
+
+			_, err := r.Exec(context.Background(), "AT+NJS=?")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Exec = %v, want %v", err, tt.want)
+			}
+			var cmdErr *rui3.CommandError
+			if !errors.As(err, &cmdErr) || cmdErr.Line != tt.reply {
+				t.Errorf("Exec = %#v, want a CommandError for %q", err, tt.reply)
+			}
+			for _, other := range tests {
+				if other.want != tt.want && errors.Is(err, other.want) {
+					t.Errorf("%q also matches %v", tt.reply, other.want)
+				}
+			}
+		})
+	}
+}
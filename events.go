@@ -0,0 +1,98 @@
+package rui3
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event is an unsolicited "+EVT:" line, e.g. "+EVT:RX_1:-70:8:UNICAST:2:1234"
+// has Name "RX_1" and the remaining fields as Params.
+type Event struct {
+	Time   time.Time `json:"time"`
+	Name   string    `json:"name"`
+	Params []string  `json:"params"`
+	Raw    string    `json:"raw"`
+}
+
+func ParseEvent(line string) (Event, bool) {
+	body, ok := strings.CutPrefix(strings.TrimSpace(line), "+EVT:")
+	if !ok {
+		return Event{}, false
+	}
+
+	fields := strings.Split(body, ":")
+	return Event{
+		Time:   time.Now(),
+		Name:   fields[0],
+		Params: fields[1:],
+		Raw:    line,
+	}, true
+}
+
+type eventBus struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+// EventRestarted is published when the module prints its boot banner
+// without being reset through ResetMCU, ResetFactoryDefaults or a firmware update,
+// e.g. after a brown-out or a press of the reset button. Raw holds the
+// banner line.
+const EventRestarted = "RESTARTED"
+
+// Subscribe returns a channel receiving every event from the module,
+// including the ones that arrive while a command is running. Events are
+// dropped when the channel is full. The channel is closed by the returned
+// cancel function or when the transport fails.
+func (r *RUI3) Subscribe() (<-chan Event, func()) {
+	return r.events.subscribe()
+}
+
+func (b *eventBus) subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 32)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+
+	if b.subs == nil {
+		b.subs = make(map[chan Event]struct{})
+	}
+	b.subs[ch] = struct{}{}
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+func (b *eventBus) publish(ev Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+func (b *eventBus) close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		close(ch)
+	}
+	b.subs = nil
+}
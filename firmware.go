@@ -0,0 +1,59 @@
+package rui3
+
+import (
+	"context"
+	"fmt"
+)
+
+// DetectFirmware reads AT+VER and AT+APIVER. Afterwards commands that need
+// a newer firmware fail with UnsupportedFirmwareError without being sent.
+// New calls it when the port is opened.
+func (r *RUI3) DetectFirmware(ctx context.Context) (Version, error) {
+	raw, err := Query(ctx, r, "AT+VER", ParseString)
+	if err != nil {
+		return Version{}, err
+	}
+	firmware, err := ParseVersion(raw)
+	if err != nil {
+		return firmware, err
+	}
+	// an updated firmware may implement other commands
+	if !r.firmware.IsZero() && firmware != r.firmware {
+		r.forgetCommands()
+	}
+
+	raw, err = r.queryOptional(ctx, "AT+APIVER")
+	if err != nil {
+		return firmware, err
+	}
+	api, _ := ParseVersion(raw)
+
+	r.firmware = firmware
+	r.api = api
+	return firmware, nil
+}
+
+// Versions returns the firmware and API versions found by DetectFirmware,
+// zero when they are unknown.
+func (r *RUI3) Versions() (firmware, api Version) {
+	return r.firmware, r.api
+}
+
+// checkFirmware fails for commands newer than the detected firmware. With
+// an unknown firmware every command is allowed.
+func (r *RUI3) checkFirmware(cmd string) error {
+	min, ok := minVersions[cmd]
+	if !ok || r.firmware.IsZero() {
+		return nil
+	}
+
+	required, err := ParseVersion(min)
+	if err != nil {
+		return fmt.Errorf("invalid minimum version for %s: %w", cmd, err)
+	}
+	if !r.firmware.AtLeast(required) {
+		return &UnsupportedFirmwareError{Command: cmd, Required: required, Current: r.firmware}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package rui3_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"tencorvids/rui3-go"
+	"tencorvids/rui3-go/sim"
+)
+
+func TestOlderFirmwareRejectsNewerCommand(t *testing.T) {
+	m := sim.New()
+	m.SetValue("AT+VER", "RUI_3.5.2_RAK3172-E")
+
+	var buf bytes.Buffer
+	r := rui3.NewWithPort(m, rui3.WithCapture(&buf))
+	defer r.Close()
+
+	_, err := r.DetectFirmware(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	captured := buf.Len()
+
+	_, err = rui3.Query(context.Background(), r, "AT+LPMLVL", rui3.ParseInt)
+	var unsupported *rui3.UnsupportedFirmwareError
+	if !errors.As(err, &unsupported) {
+		t.Fatalf("Query = %v, want UnsupportedFirmwareError", err)
+	}
+	if !errors.Is(err, rui3.ErrUnsupportedFirmware) {
+		t.Errorf("%v does not match ErrUnsupportedFirmware", err)
+	}
+	if unsupported.Command != "AT+LPMLVL" {
+		t.Errorf("Command = %q, want AT+LPMLVL", unsupported.Command)
+	}
+	if buf.Len() != captured {
+		t.Errorf("the rejected command was sent: %s", buf.Bytes()[captured:])
+	}
+
+	// the same command goes through once the module runs a newer release
+	m.SetValue("AT+VER", "RUI_4.0.6_RAK3172-E")
+	_, err = r.DetectFirmware(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rui3.Query(context.Background(), r, "AT+LPMLVL", rui3.ParseInt)
+	if err != nil {
+		t.Errorf("Query on a newer firmware: %v", err)
+	}
+}
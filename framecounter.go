@@ -0,0 +1,248 @@
+package rui3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type FrameCounters struct {
+	Uplink   uint32 `json:"uplink"`
+	Downlink uint32 `json:"downlink"`
+}
+
+// FrameCounterStore persists frame counters across host restarts so ABP
+// sessions do not fall back to zero and get rejected by the network server.
+type FrameCounterStore interface {
+	Load() (FrameCounters, error)
+	Save(counters FrameCounters) error
+}
+
+type MemoryFrameCounterStore struct {
+	mu       sync.Mutex
+	counters FrameCounters
+}
+
+func NewMemoryFrameCounterStore() *MemoryFrameCounterStore {
+	return &MemoryFrameCounterStore{}
+}
+
+func (s *MemoryFrameCounterStore) Load() (FrameCounters, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counters, nil
+}
+
+func (s *MemoryFrameCounterStore) Save(counters FrameCounters) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.counters = counters
+	return nil
+}
+
+type FileFrameCounterStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+func NewFileFrameCounterStore(path string) *FileFrameCounterStore {
+	return &FileFrameCounterStore{path: path}
+}
+
+func (s *FileFrameCounterStore) Load() (FrameCounters, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var counters FrameCounters
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return counters, nil
+	}
+	if err != nil {
+		return counters, fmt.Errorf("failed to read frame counters %s: %w", s.path, err)
+	}
+
+	err = json.Unmarshal(data, &counters)
+	if err != nil {
+		return counters, fmt.Errorf("failed to decode frame counters %s: %w", s.path, err)
+	}
+
+	return counters, nil
+}
+
+func (s *FileFrameCounterStore) Save(counters FrameCounters) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := json.Marshal(counters)
+	if err != nil {
+		return fmt.Errorf("failed to encode frame counters: %w", err)
+	}
+
+	err = writeFileAtomic(s.path, data)
+	if err != nil {
+		return fmt.Errorf("failed to write frame counters %s: %w", s.path, err)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes to a temporary file and renames it so a power cut
+// never leaves a truncated file behind.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// SetFrameCounterStore loads the persisted counters, writes them back to
+// the module where the firmware allows it and keeps the store updated on
+// every successful Send and every downlink.
+func (r *RUI3) SetFrameCounterStore(store FrameCounterStore) error {
+	counters, err := store.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load frame counters: %w", err)
+	}
+
+	r.frameCounterMu.Lock()
+	r.frameCounterStore = store
+	r.frameCounters = counters
+	r.frameCounterMu.Unlock()
+
+	return r.RestoreFrameCounters()
+}
+
+// RestoreFrameCounters raises the module's counters to the tracked ones.
+// A counter is only written when the module's own value is lower, so a
+// stale or empty store never moves a counter back, which the network
+// server would treat as a replay.
+func (r *RUI3) RestoreFrameCounters() error {
+	ctx := context.Background()
+
+	r.frameCounterMu.Lock()
+	tracked := r.frameCounters
+	r.frameCounterMu.Unlock()
+
+	counters := []struct {
+		cmd     string
+		tracked *uint32
+	}{
+		{"AT+UPCNT", &tracked.Uplink},
+		{"AT+DOWNCNT", &tracked.Downlink},
+	}
+	for _, c := range counters {
+		current, err := Query(ctx, r, c.cmd, ParseUint32)
+		if errors.Is(err, ErrCommandNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if current >= *c.tracked {
+			*c.tracked = current
+			continue
+		}
+
+		err = r.Set(ctx, c.cmd, *c.tracked)
+		if err != nil {
+			return err
+		}
+	}
+
+	// downlinks counted meanwhile are kept, the counters only grow
+	return r.updateFrameCounters(func(counters *FrameCounters) {
+		counters.Uplink = max(counters.Uplink, tracked.Uplink)
+		counters.Downlink = max(counters.Downlink, tracked.Downlink)
+	})
+}
+
+// GetFrameCounters reads the counters from the module when the firmware
+// supports it and falls back to the counters tracked by Send otherwise.
+func (r *RUI3) GetFrameCounters() (FrameCounters, error) {
+	uplink, err := Query(context.Background(), r, "AT+UPCNT", ParseUint32)
+	if errors.Is(err, ErrCommandNotFound) {
+		r.frameCounterMu.Lock()
+		defer r.frameCounterMu.Unlock()
+		return r.frameCounters, nil
+	}
+	if err != nil {
+		return FrameCounters{}, err
+	}
+
+	downlink, err := Query(context.Background(), r, "AT+DOWNCNT", ParseUint32)
+	if err != nil && !errors.Is(err, ErrCommandNotFound) {
+		return FrameCounters{}, err
+	}
+	hasDownlink := err == nil
+
+	var counters FrameCounters
+	err = r.updateFrameCounters(func(c *FrameCounters) {
+		c.Uplink = uplink
+		if hasDownlink {
+			c.Downlink = downlink
+		}
+		counters = *c
+	})
+	return counters, err
+}
+
+// updateFrameCounters changes the tracked counters and saves them.
+func (r *RUI3) updateFrameCounters(update func(*FrameCounters)) error {
+	r.frameCounterMu.Lock()
+	defer r.frameCounterMu.Unlock()
+
+	update(&r.frameCounters)
+	if r.frameCounterStore == nil {
+		return nil
+	}
+
+	err := r.frameCounterStore.Save(r.frameCounters)
+	if err != nil {
+		return fmt.Errorf("failed to save frame counters: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RUI3) trackUplink() error {
+	return r.updateFrameCounters(func(c *FrameCounters) {
+		c.Uplink++
+	})
+}
+
+// countDownlinks tracks the downlink counter from RX events, which arrive
+// after the send that caused them has already returned.
+func (r *RUI3) countDownlinks(events <-chan Event) {
+	for ev := range events {
+		if !strings.HasPrefix(ev.Name, "RX_") {
+			continue
+		}
+		err := r.updateFrameCounters(func(c *FrameCounters) {
+			c.Downlink++
+		})
+		if err != nil {
+			r.logger.Debug("failed to save frame counters", "error", err)
+		}
+	}
+}
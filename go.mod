@@ -2,9 +2,13 @@ module tencorvids/rui3-go
 
 go 1.24.2
 
-require go.bug.st/serial v1.6.4
+require (
+	go.bug.st/serial v1.6.4
+	golang.org/x/term v0.32.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/creack/goselect v0.1.2 // indirect
-	golang.org/x/sys v0.19.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
 )
@@ -0,0 +1,90 @@
+package rui3
+
+import (
+	"context"
+	"errors"
+)
+
+// DeviceInfo collects the identification and version queries of a module.
+// Fields for commands missing on the connected firmware are left empty.
+type DeviceInfo struct {
+	HardwareModel  string  `json:"hardwareModel"`
+	HardwareID     string  `json:"hardwareID"`
+	SerialNumber   string  `json:"serialNumber"`
+	UID            string  `json:"uid"`
+	Alias          string  `json:"alias"`
+	Firmware       Version `json:"firmware"`
+	API            Version `json:"api"`
+	Bootloader     Version `json:"bootloader"`
+	CLI            Version `json:"cli"`
+	BuildTime      string  `json:"buildTime"`
+	RepoInfo       string  `json:"repoInfo"`
+	BatteryVoltage float64 `json:"batteryVoltage"`
+}
+
+func (r *RUI3) GetDeviceInfo(ctx context.Context) (DeviceInfo, error) {
+	var info DeviceInfo
+
+	strs := []struct {
+		cmd string
+		dst *string
+	}{
+		{"AT+HWMODEL", &info.HardwareModel},
+		{"AT+HWID", &info.HardwareID},
+		{"AT+SN", &info.SerialNumber},
+		{"AT+UID", &info.UID},
+		{"AT+ALIAS", &info.Alias},
+		{"AT+BUILDTIME", &info.BuildTime},
+		{"AT+REPOINFO", &info.RepoInfo},
+	}
+	for _, s := range strs {
+		value, err := r.queryOptional(ctx, s.cmd)
+		if err != nil {
+			return info, err
+		}
+		*s.dst = value
+	}
+
+	versions := []struct {
+		cmd string
+		dst *Version
+	}{
+		{"AT+VER", &info.Firmware},
+		{"AT+APIVER", &info.API},
+		{"AT+BOOTVER", &info.Bootloader},
+		{"AT+CLIVER", &info.CLI},
+	}
+	for _, v := range versions {
+		value, err := r.queryOptional(ctx, v.cmd)
+		if err != nil {
+			return info, err
+		}
+		if value == "" {
+			continue
+		}
+		// unparsable versions are kept as raw strings only
+		*v.dst, _ = ParseVersion(value)
+	}
+
+	battery, err := Query(ctx, r, "AT+BAT", ParseFloat)
+	if err != nil && !errors.Is(err, ErrCommandNotFound) && !errors.Is(err, ErrUnsupportedFirmware) {
+		return info, err
+	}
+	info.BatteryVoltage = battery
+
+	return info, nil
+}
+
+// queryOptional reads a value and returns an empty string when the
+// firmware does not know the command.
+func (r *RUI3) queryOptional(ctx context.Context, cmd string) (string, error) {
+	if !r.Supports(cmd) {
+		return "", nil
+	}
+
+	value, err := Query(ctx, r, cmd, ParseString)
+	if errors.Is(err, ErrCommandNotFound) || errors.Is(err, ErrUnsupportedFirmware) {
+		return "", nil
+	}
+	return value, err
+}
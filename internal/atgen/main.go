@@ -0,0 +1,475 @@
+// Command atgen generates the typed RUI3 methods, the command reference
+// and the simulator registers from commands.json.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+type spec struct {
+	Commands []command `json:"commands"`
+}
+
+type command struct {
+	Name        string `json:"name"`
+	Method      string `json:"method"`
+	Description string `json:"description"`
+	Read        bool   `json:"read"`
+	Write       bool   `json:"write"`
+	Type        string `json:"type"`
+	Min         *int64 `json:"min"`
+	Max         *int64 `json:"max"`
+	Length      int    `json:"length"`
+	Enum        *enum  `json:"enum"`
+	Default     string `json:"default"`
+	MinVersion  string `json:"min_version"`
+	Handler     bool   `json:"handler"`
+	Secret      bool   `json:"secret"`
+	Restarts    bool   `json:"restarts"`
+}
+
+type enum struct {
+	Type   string      `json:"type"`
+	Values []enumValue `json:"values"`
+}
+
+type enumValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Text is the name used for the value in text encodings, the constant name
+// without its type prefix.
+func (v enumValue) Text(typ string) string {
+	return strings.TrimPrefix(v.Name, typ)
+}
+
+func main() {
+	specPath := flag.String("spec", "commands.json", "command spec")
+	methodsOut := flag.String("methods", "commands_gen.go", "generated methods")
+	docsOut := flag.String("docs", "COMMANDS.md", "generated command reference")
+	simOut := flag.String("sim", filepath.Join("sim", "registers_gen.go"), "generated simulator registers")
+	flag.Parse()
+
+	data, err := os.ReadFile(*specPath)
+	if err != nil {
+		log.Fatalf("failed to read spec: %v", err)
+	}
+
+	var s spec
+	err = json.Unmarshal(data, &s)
+	if err != nil {
+		log.Fatalf("failed to decode spec %s: %v", *specPath, err)
+	}
+
+	for _, c := range s.Commands {
+		err = c.validate()
+		if err != nil {
+			log.Fatalf("invalid command %s: %v", c.Name, err)
+		}
+	}
+
+	err = render(*methodsOut, methodsTemplate, s, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = render(*docsOut, docsTemplate, s, false)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = render(*simOut, simTemplate, s, true)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (c command) validate() error {
+	if !strings.HasPrefix(c.Name, "AT") {
+		return fmt.Errorf("name must start with AT")
+	}
+
+	switch c.Type {
+	case "", "string", "int", "bool", "float":
+	case "hex":
+		if c.Length == 0 {
+			return fmt.Errorf("hex commands need a length")
+		}
+	case "enum":
+		if c.Enum == nil || c.Enum.Type == "" || len(c.Enum.Values) == 0 {
+			return fmt.Errorf("enum commands need an enum type and values")
+		}
+	default:
+		return fmt.Errorf("unknown type %q", c.Type)
+	}
+
+	if c.Method != "" && c.Type == "float" && c.Write {
+		return fmt.Errorf("writable float commands are not supported")
+	}
+
+	return nil
+}
+
+func render(path string, tmpl *template.Template, s spec, gofmt bool) error {
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, s)
+	if err != nil {
+		return fmt.Errorf("failed to render %s: %w", path, err)
+	}
+
+	out := buf.Bytes()
+	if gofmt {
+		out, err = format.Source(out)
+		if err != nil {
+			return fmt.Errorf("failed to format %s: %w", path, err)
+		}
+	}
+
+	return os.WriteFile(path, out, 0o644)
+}
+
+func (c command) GoType() string {
+	if c.Secret {
+		return "Secret"
+	}
+	switch c.Type {
+	case "int":
+		return "int"
+	case "bool":
+		return "bool"
+	case "float":
+		return "float64"
+	case "enum":
+		return c.Enum.Type
+	}
+	return "string"
+}
+
+func (c command) Parser() string {
+	if c.Secret {
+		return "ParseSecret"
+	}
+	switch c.Type {
+	case "int":
+		return "ParseInt"
+	case "bool":
+		return "ParseBool"
+	case "float":
+		return "ParseFloat"
+	case "enum":
+		return "parse" + c.Enum.Type
+	}
+	return "ParseString"
+}
+
+// Label turns the method name into the lower case words used in errors,
+// e.g. RX2DataRate becomes "rx2 data rate".
+func (c command) Label() string {
+	var words []string
+	runes := []rune(c.Method)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+		if unicode.IsUpper(cur) && (unicode.IsLower(prev) || unicode.IsDigit(prev)) ||
+			unicode.IsUpper(cur) && unicode.IsUpper(prev) && nextLower ||
+			unicode.IsDigit(cur) && unicode.IsLower(prev) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+	words = append(words, string(runes[start:]))
+	return strings.ToLower(strings.Join(words, " "))
+}
+
+func (c command) Access() string {
+	switch {
+	case c.Read && c.Write:
+		return "read/write"
+	case c.Read:
+		return "read"
+	case c.Write:
+		return "write"
+	}
+	return "exec"
+}
+
+func (c command) Range() string {
+	switch {
+	case c.Min != nil && c.Max != nil:
+		return fmt.Sprintf("%d-%d", *c.Min, *c.Max)
+	case c.Type == "hex":
+		return fmt.Sprintf("%d bytes", c.Length)
+	case c.Type == "enum":
+		values := make([]string, len(c.Enum.Values))
+		for i, v := range c.Enum.Values {
+			values[i] = v.Value + "=" + v.Name
+		}
+		return strings.Join(values, ", ")
+	}
+	return ""
+}
+
+func (c command) Validator() string {
+	switch c.Type {
+	case "int":
+		if c.Min != nil && c.Max != nil {
+			return fmt.Sprintf("ValidateInt(%d, %d)", *c.Min, *c.Max)
+		}
+		return "ValidateInt(math.MinInt64, math.MaxInt64)"
+	case "bool":
+		return "ValidateBool"
+	case "hex":
+		return fmt.Sprintf("ValidateHex(%d)", c.Length)
+	case "enum":
+		values := make([]string, len(c.Enum.Values))
+		for i, v := range c.Enum.Values {
+			values[i] = fmt.Sprintf("%q", v.Value)
+		}
+		return "ValidateEnum(" + strings.Join(values, ", ") + ")"
+	}
+	return "nil"
+}
+
+func (s spec) Methods() []command {
+	var commands []command
+	for _, c := range s.Commands {
+		if c.Method != "" {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func (s spec) Enums() []command {
+	var commands []command
+	for _, c := range s.Methods() {
+		if c.Enum != nil {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func (s spec) Registers() []command {
+	var commands []command
+	for _, c := range s.Commands {
+		if (c.Read || c.Write) && !c.Handler {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+// Unregistered lists the commands the simulator implements in code rather
+// than as registers.
+func (s spec) Unregistered() []command {
+	var commands []command
+	for _, c := range s.Commands {
+		if !(c.Read || c.Write) || c.Handler {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func (s spec) Secrets() []command {
+	var commands []command
+	for _, c := range s.Commands {
+		if c.Secret {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func (s spec) Restarting() []command {
+	var commands []command
+	for _, c := range s.Commands {
+		if c.Restarts {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func (s spec) Versioned() []command {
+	var commands []command
+	for _, c := range s.Commands {
+		if c.MinVersion != "" {
+			commands = append(commands, c)
+		}
+	}
+	return commands
+}
+
+func (s spec) NeedsMath() bool {
+	for _, c := range s.Registers() {
+		if c.Type == "int" && (c.Min == nil || c.Max == nil) {
+			return true
+		}
+	}
+	return false
+}
+
+var methodsTemplate = template.Must(template.New("methods").Parse(`// Code generated by atgen from commands.json. DO NOT EDIT.
+
+package rui3
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// KnownCommands lists the commands described in commands.json.
+var KnownCommands = []string{
+{{- range .Commands}}
+	"{{.Name}}",
+{{- end}}
+}
+
+// minVersions holds the first firmware release that supports a command.
+var minVersions = map[string]string{
+{{- range .Versioned}}
+	"{{.Name}}": "{{.MinVersion}}",
+{{- end}}
+}
+
+// secretCommands carry keys, their values are redacted in logs.
+var secretCommands = []string{
+{{- range .Secrets}}
+	"{{.Name}}",
+{{- end}}
+}
+
+// restartCommands restart the module when they are executed or set.
+var restartCommands = map[string]bool{
+{{- range .Restarting}}
+	"{{.Name}}": true,
+{{- end}}
+}
+{{range .Enums}}{{$type := .Enum.Type}}
+type {{$type}} int
+
+const (
+{{- range .Enum.Values}}
+	{{.Name}} {{$type}} = {{.Value}}
+{{- end}}
+)
+
+func parse{{.Enum.Type}}(value string) ({{.Enum.Type}}, error) {
+	return ParseEnum(map[string]{{.Enum.Type}}{
+{{- range .Enum.Values}}
+		"{{.Value}}": {{.Name}},
+{{- end}}
+	})(value)
+}
+
+func (v {{$type}}) String() string {
+	switch v {
+{{- range .Enum.Values}}
+	case {{.Name}}:
+		return "{{.Text $type}}"
+{{- end}}
+	}
+	return fmt.Sprintf("{{$type}}(%d)", int(v))
+}
+
+func (v {{$type}}) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+func (v *{{$type}}) UnmarshalText(text []byte) error {
+	switch {
+{{- range .Enum.Values}}
+	case strings.EqualFold(string(text), "{{.Text $type}}"):
+		*v = {{.Name}}
+{{- end}}
+	default:
+		return fmt.Errorf("unknown {{$type}} %q", text)
+	}
+	return nil
+}
+{{end}}
+{{- range .Methods}}
+{{- if .Read}}
+
+// Get{{.Method}} reads {{.Name}}: {{.Description}}.
+func (r *RUI3) Get{{.Method}}() ({{.GoType}}, error) {
+	return Query(context.Background(), r, "{{.Name}}", {{.Parser}})
+}
+{{- end}}
+{{- if .Write}}
+
+// Set{{.Method}} writes {{.Name}}: {{.Description}}.
+func (r *RUI3) Set{{.Method}}(value {{.GoType}}) error {
+{{- if and .Min .Max}}
+	if value < {{.Min}} || value > {{.Max}} {
+		return fmt.Errorf("invalid {{.Label}}: %d", value)
+	}
+
+{{else if eq .Type "hex"}}
+	if err := validateHex({{if .Secret}}value.Reveal(){{else}}value{{end}}, {{.Length}}); err != nil {
+		return fmt.Errorf("invalid {{.Label}}: %w", err)
+	}
+
+{{else if .Enum}}
+	if _, err := parse{{.Enum.Type}}(fmt.Sprint(int(value))); err != nil {
+		return fmt.Errorf("invalid {{.Label}}: %d", value)
+	}
+
+{{end -}}
+	return r.Set(context.Background(), "{{.Name}}", {{if .Enum}}int(value){{else}}value{{end}})
+}
+{{- end}}
+{{- end}}
+`))
+
+var docsTemplate = template.Must(template.New("docs").Parse(`<!-- Code generated by atgen from commands.json. DO NOT EDIT. -->
+
+# AT command reference
+
+| Command | Method | Access | Type | Range | Default | Min firmware | Description |
+| --- | --- | --- | --- | --- | --- | --- | --- |
+{{- range .Commands}}
+| ` + "`{{.Name}}`" + ` | {{if .Method}}{{if .Read}}` + "`Get{{.Method}}`" + `{{end}}{{if and .Read .Write}} / {{end}}{{if .Write}}` + "`Set{{.Method}}`" + `{{end}}{{end}} | {{.Access}} | {{.Type}} | {{.Range}} | {{.Default}} | {{.MinVersion}} | {{.Description}} |
+{{- end}}
+`))
+
+var simTemplate = template.Must(template.New("sim").Parse(`// Code generated by atgen from commands.json. DO NOT EDIT.
+
+package sim
+{{if .NeedsMath}}
+import "math"
+{{end}}
+func (m *Module) registerGenerated() {
+{{- range .Registers}}
+	m.Define("{{.Name}}", Register{
+		Value:    {{printf "%q" .Default}},
+		Read:     {{.Read}},
+		Write:    {{.Write}},
+		Validate: {{.Validator}},
+		Help:     {{printf "%q" .Description}},
+	})
+{{- end}}
+}
+
+// commandHelp describes the commands without a register for AT?.
+var commandHelp = map[string]string{
+{{- range .Unregistered}}
+	"{{.Name}}": {{printf "%q" .Description}},
+{{- end}}
+}
+`))
@@ -1,82 +1,42 @@
 package rui3
 
 import (
+	"context"
 	"fmt"
-	"strings"
-	"time"
 )
 
 func (r *RUI3) GetDevEUI() (string, error) {
-	err := r.SendRawCommand("AT+DEVEUI=?")
-	if err != nil {
-		return "", fmt.Errorf("failed to send deveui command: %w", err)
-	}
-
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return "", fmt.Errorf("failed to receive deveui response: %w", err)
-	}
-
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "AT+DEVEUI=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1]), nil
-			}
-		}
-	}
-
-	return "", fmt.Errorf("DEVEUI not found in response: %s", response)
+	return Query(context.Background(), r, "AT+DEVEUI", ParseString)
 }
 
-func (r *RUI3) GetAppKey() (string, error) {
-	err := r.SendRawCommand("AT+APPKEY=?")
-	if err != nil {
-		return "", fmt.Errorf("failed to send appkey command: %w", err)
-	}
+func (r *RUI3) GetAppKey() (Secret, error) {
+	return Query(context.Background(), r, "AT+APPKEY", ParseSecret)
+}
 
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return "", fmt.Errorf("failed to receive appkey response: %w", err)
-	}
+func (r *RUI3) GetAppEUI() (string, error) {
+	return Query(context.Background(), r, "AT+APPEUI", ParseString)
+}
 
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "AT+APPKEY=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1]), nil
-			}
-		}
+func (r *RUI3) SetDevEUI(devEUI string) error {
+	if err := validateHex(devEUI, 8); err != nil {
+		return fmt.Errorf("invalid DevEUI: %w", err)
 	}
 
-	return "", fmt.Errorf("APPKEY not found in response: %s", response)
+	return r.Set(context.Background(), "AT+DEVEUI", devEUI)
 }
 
-func (r *RUI3) GetAppEUI() (string, error) {
-	err := r.SendRawCommand("AT+APPEUI=?")
-	if err != nil {
-		return "", fmt.Errorf("failed to send appeui command: %w", err)
+func (r *RUI3) SetAppKey(appKey Secret) error {
+	if err := validateHex(appKey.Reveal(), 16); err != nil {
+		return fmt.Errorf("invalid AppKey: %w", err)
 	}
 
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return "", fmt.Errorf("failed to receive appeui response: %w", err)
-	}
+	return r.Set(context.Background(), "AT+APPKEY", appKey)
+}
 
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "AT+APPEUI=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1]), nil
-			}
-		}
+func (r *RUI3) SetAppEUI(appEUI string) error {
+	if err := validateHex(appEUI, 8); err != nil {
+		return fmt.Errorf("invalid AppEUI: %w", err)
 	}
 
-	return "", fmt.Errorf("APPEUI not found in response: %s", response)
+	return r.Set(context.Background(), "AT+APPEUI", appEUI)
 }
@@ -0,0 +1,294 @@
+package rui3
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+var ErrKeysNotFound = errors.New("keys not found")
+
+// DeviceKeys are the keys of one device. OTAA devices use JoinEUI and
+// AppKey, ABP devices DevAddr and the session keys.
+type DeviceKeys struct {
+	JoinEUI string `json:"joinEUI,omitempty"`
+	AppKey  Secret `json:"appKey,omitempty"`
+	DevAddr string `json:"devAddr,omitempty"`
+	AppSKey Secret `json:"appSKey,omitempty"`
+	NwkSKey Secret `json:"nwkSKey,omitempty"`
+}
+
+// KeyStore keeps device keys by DevEUI so they do not have to be passed
+// around or stored in plain text.
+type KeyStore interface {
+	Load(devEUI string) (DeviceKeys, error)
+	Save(devEUI string, keys DeviceKeys) error
+}
+
+type MemoryKeyStore struct {
+	mu   sync.Mutex
+	keys map[string]DeviceKeys
+}
+
+func NewMemoryKeyStore() *MemoryKeyStore {
+	return &MemoryKeyStore{keys: make(map[string]DeviceKeys)}
+}
+
+func (s *MemoryKeyStore) Load(devEUI string) (DeviceKeys, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, ok := s.keys[strings.ToUpper(devEUI)]
+	if !ok {
+		return keys, fmt.Errorf("%w: %s", ErrKeysNotFound, devEUI)
+	}
+	return keys, nil
+}
+
+func (s *MemoryKeyStore) Save(devEUI string, keys DeviceKeys) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.keys[strings.ToUpper(devEUI)] = keys
+	return nil
+}
+
+const keyStoreIterations = 600000
+
+// storedKeys is DeviceKeys without the redaction, as encrypted on disk.
+type storedKeys struct {
+	JoinEUI string `json:"joinEUI,omitempty"`
+	AppKey  string `json:"appKey,omitempty"`
+	DevAddr string `json:"devAddr,omitempty"`
+	AppSKey string `json:"appSKey,omitempty"`
+	NwkSKey string `json:"nwkSKey,omitempty"`
+}
+
+type keyFile struct {
+	Salt  []byte `json:"salt"`
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+// EncryptedFileKeyStore keeps all keys in one file sealed with AES-256-GCM.
+// The key is derived from a passphrase with PBKDF2-SHA256.
+type EncryptedFileKeyStore struct {
+	mu         sync.Mutex
+	path       string
+	passphrase Secret
+	salt       []byte
+	aead       cipher.AEAD
+}
+
+func NewEncryptedFileKeyStore(path string, passphrase Secret) *EncryptedFileKeyStore {
+	return &EncryptedFileKeyStore{path: path, passphrase: passphrase}
+}
+
+func (s *EncryptedFileKeyStore) Load(devEUI string) (DeviceKeys, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return DeviceKeys{}, err
+	}
+
+	stored, ok := all[strings.ToUpper(devEUI)]
+	if !ok {
+		return DeviceKeys{}, fmt.Errorf("%w: %s", ErrKeysNotFound, devEUI)
+	}
+
+	return DeviceKeys{
+		JoinEUI: stored.JoinEUI,
+		AppKey:  Secret(stored.AppKey),
+		DevAddr: stored.DevAddr,
+		AppSKey: Secret(stored.AppSKey),
+		NwkSKey: Secret(stored.NwkSKey),
+	}, nil
+}
+
+func (s *EncryptedFileKeyStore) Save(devEUI string, keys DeviceKeys) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	all, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	all[strings.ToUpper(devEUI)] = storedKeys{
+		JoinEUI: keys.JoinEUI,
+		AppKey:  keys.AppKey.Reveal(),
+		DevAddr: keys.DevAddr,
+		AppSKey: keys.AppSKey.Reveal(),
+		NwkSKey: keys.NwkSKey.Reveal(),
+	}
+
+	return s.write(all)
+}
+
+// aeadFor derives the key once per salt, the derivation is deliberately slow.
+func (s *EncryptedFileKeyStore) aeadFor(salt []byte) (cipher.AEAD, error) {
+	if s.aead != nil && string(salt) == string(s.salt) {
+		return s.aead, nil
+	}
+
+	key, err := pbkdf2.Key(sha256.New, s.passphrase.Reveal(), salt, keyStoreIterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	s.salt = salt
+	s.aead = aead
+	return aead, nil
+}
+
+func (s *EncryptedFileKeyStore) read() (map[string]storedKeys, error) {
+	all := make(map[string]storedKeys)
+
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key store %s: %w", s.path, err)
+	}
+
+	var file keyFile
+	err = json.Unmarshal(data, &file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key store %s: %w", s.path, err)
+	}
+
+	aead, err := s.aeadFor(file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt key store %s, wrong passphrase?", s.path)
+	}
+
+	err = json.Unmarshal(plain, &all)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode key store %s: %w", s.path, err)
+	}
+
+	return all, nil
+}
+
+func (s *EncryptedFileKeyStore) write(all map[string]storedKeys) error {
+	plain, err := json.Marshal(all)
+	if err != nil {
+		return fmt.Errorf("failed to encode keys: %w", err)
+	}
+
+	salt := s.salt
+	if salt == nil {
+		salt = make([]byte, 16)
+		rand.Read(salt)
+	}
+	aead, err := s.aeadFor(salt)
+	if err != nil {
+		return err
+	}
+
+	file := keyFile{Salt: salt, Nonce: make([]byte, aead.NonceSize())}
+	rand.Read(file.Nonce)
+	file.Data = aead.Seal(nil, file.Nonce, plain, nil)
+
+	data, err := json.Marshal(file)
+	if err != nil {
+		return fmt.Errorf("failed to encode key store: %w", err)
+	}
+
+	err = writeFileAtomic(s.path, data)
+	if err != nil {
+		return fmt.Errorf("failed to write key store %s: %w", s.path, err)
+	}
+
+	return nil
+}
+
+// ActivateABP switches the module to ABP with the given session.
+func (r *RUI3) ActivateABP(ctx context.Context, devAddr string, appSKey, nwkSKey Secret) error {
+	if err := validateHex(devAddr, 4); err != nil {
+		return fmt.Errorf("invalid device address: %w", err)
+	}
+	if err := validateHex(appSKey.Reveal(), 16); err != nil {
+		return fmt.Errorf("invalid app session key: %w", err)
+	}
+	if err := validateHex(nwkSKey.Reveal(), 16); err != nil {
+		return fmt.Errorf("invalid network session key: %w", err)
+	}
+
+	steps := []struct {
+		cmd   string
+		value any
+	}{
+		{"AT+NJM", int(JoinModeABP)},
+		{"AT+DEVADDR", devAddr},
+		{"AT+APPSKEY", appSKey},
+		{"AT+NWKSKEY", nwkSKey},
+	}
+	for _, step := range steps {
+		err := r.Set(ctx, step.cmd, step.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadKeys looks up the module's DevEUI in the store and writes the keys
+// found there. A stored ABP session switches the module to ABP.
+func (r *RUI3) LoadKeys(ctx context.Context, store KeyStore) (DeviceKeys, error) {
+	devEUI, err := Query(ctx, r, "AT+DEVEUI", ParseString)
+	if err != nil {
+		return DeviceKeys{}, err
+	}
+
+	keys, err := store.Load(devEUI)
+	if err != nil {
+		return keys, err
+	}
+
+	if keys.JoinEUI != "" {
+		err = r.Set(ctx, "AT+APPEUI", keys.JoinEUI)
+		if err != nil {
+			return keys, err
+		}
+	}
+	if keys.AppKey != "" {
+		err = r.Set(ctx, "AT+APPKEY", keys.AppKey)
+		if err != nil {
+			return keys, err
+		}
+	}
+	if keys.DevAddr != "" {
+		err = r.ActivateABP(ctx, keys.DevAddr, keys.AppSKey, keys.NwkSKey)
+		if err != nil {
+			return keys, err
+		}
+	}
+
+	return keys, nil
+}
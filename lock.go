@@ -0,0 +1,132 @@
+package rui3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// WithPassword unlocks a module locked with Lock when the port is opened.
+// The password is only sent when the module turns out to be locked.
+func WithPassword(password Secret) Option {
+	return func(o *options) {
+		o.password = password
+	}
+}
+
+func validatePassword(password Secret) error {
+	p := password.Reveal()
+	if len(p) < 1 || len(p) > 8 {
+		return fmt.Errorf("invalid password: must be 1 to 8 characters")
+	}
+	for _, c := range p {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return fmt.Errorf("invalid password: must be letters and digits")
+		}
+	}
+	return nil
+}
+
+// SetPassword sets the password needed to unlock the port after Lock.
+func (r *RUI3) SetPassword(ctx context.Context, password Secret) error {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	return r.Set(ctx, "AT+PWORD", password)
+}
+
+// ClearPassword removes the password. The port can not be locked without
+// one.
+func (r *RUI3) ClearPassword(ctx context.Context) error {
+	return r.Set(ctx, "AT+PWORD")
+}
+
+// Lock locks the port. The module ignores every command but AT+PWORD until
+// it is unlocked, and stays locked across restarts.
+func (r *RUI3) Lock(ctx context.Context) error {
+	response, err := r.Exec(ctx, "AT+LOCK")
+	if err != nil {
+		return err
+	}
+
+	if !strings.Contains(response, "OK") {
+		return fmt.Errorf("failed to lock: %s", response)
+	}
+
+	r.locked = true
+	return nil
+}
+
+// Unlock unlocks the port. A wrong password returns ErrLocked. On an
+// unlocked module nothing is sent, AT+PWORD would set a new password there,
+// so DetectLock runs first.
+func (r *RUI3) Unlock(ctx context.Context, password Secret) error {
+	if err := validatePassword(password); err != nil {
+		return err
+	}
+
+	locked, err := r.DetectLock(ctx)
+	if err != nil {
+		return err
+	}
+	if !locked {
+		r.password = password
+		return nil
+	}
+
+	err = r.Set(ctx, "AT+PWORD", password)
+	if errors.Is(err, ErrParamError) {
+		return fmt.Errorf("%w: wrong password", ErrLocked)
+	}
+	if err != nil {
+		return err
+	}
+
+	r.locked = false
+	r.password = password
+	return nil
+}
+
+// Locked reports whether the port is locked, by this client or as found
+// by DetectLock. Commands other than Unlock fail with ErrLocked meanwhile.
+func (r *RUI3) Locked() bool {
+	return r.locked
+}
+
+// DetectLock reports whether the module is locked. A module locked by
+// another client stays silent, so commands time out until the lock is
+// detected here, by Unlock or when the port is opened WithPassword.
+func (r *RUI3) DetectLock(ctx context.Context) (bool, error) {
+	if r.locked {
+		return true, nil
+	}
+
+	if r.probe(ctx) {
+		return false, nil
+	}
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	if !r.probeLocked(ctx) {
+		return false, errors.New("module not responding")
+	}
+
+	r.locked = true
+	return true, nil
+}
+
+// probeLocked reports whether a module that did not answer AT is locked.
+// A locked module ignores everything but AT+PWORD and rejects the query of
+// the password. An unlocked one rejects it as well, so AT has to stay
+// unanswered afterwards too before the module counts as locked.
+func (r *RUI3) probeLocked(ctx context.Context) bool {
+	attemptCtx, cancel := context.WithTimeout(ctx, probeTimeout)
+	_, err := r.Exec(attemptCtx, "AT+PWORD=?")
+	cancel()
+	if !errors.Is(err, ErrParamError) {
+		return false
+	}
+	return !r.probe(ctx)
+}
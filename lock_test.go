@@ -0,0 +1,128 @@
+package rui3_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+
+	"tencorvids/rui3-go"
+	"tencorvids/rui3-go/rfc2217"
+	"tencorvids/rui3-go/sim"
+)
+
+// lockSim locks the module the way another client would.
+func lockSim(t *testing.T, m *sim.Module, password string) {
+	t.Helper()
+	m.Write([]byte("AT+PWORD=" + password + "\r\nAT+LOCK\r\n"))
+	got := make([]byte, 64)
+	n, _ := m.Read(got)
+	if string(got[:n]) != "OK\r\nOK\r\n" {
+		t.Fatalf("locking the module returned %q", got[:n])
+	}
+}
+
+// baudPort only passes data to the module at its baud rate, as if
+// everything sent at another rate were lost.
+type baudPort struct {
+	*sim.Module
+	baud int
+
+	mu   sync.Mutex
+	mode int
+}
+
+func (p *baudPort) SetMode(mode *serial.Mode) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mode = mode.BaudRate
+	return nil
+}
+
+func (p *baudPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	mode := p.mode
+	p.mu.Unlock()
+	if mode != p.baud {
+		return len(b), nil
+	}
+	return p.Module.Write(b)
+}
+
+func serve(t *testing.T, port rfc2217.Port) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go rfc2217.NewServer(port).Serve(ln)
+	return "rfc2217://" + ln.Addr().String()
+}
+
+func TestAutoBaudUnlocksLockedModule(t *testing.T) {
+	m := sim.New()
+	lockSim(t, m, "s3cret")
+
+	r, err := rui3.New(serve(t, &baudPort{Module: m, baud: 9600}), rui3.WithAutoBaud(), rui3.WithPassword("s3cret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if r.Locked() {
+		t.Error("module still locked after New")
+	}
+	devEUI, err := rui3.Query(context.Background(), r, "AT+DEVEUI", rui3.ParseString)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if devEUI != m.Value("AT+DEVEUI") {
+		t.Errorf("DevEUI = %q, want %q", devEUI, m.Value("AT+DEVEUI"))
+	}
+}
+
+func TestDetectLock(t *testing.T) {
+	m := sim.New()
+	r := rui3.NewWithPort(m)
+	defer r.Close()
+
+	locked, err := r.DetectLock(context.Background())
+	if err != nil || locked {
+		t.Fatalf("DetectLock on an unlocked module = %v, %v", locked, err)
+	}
+
+	lockSim(t, m, "s3cret")
+
+	// a silent module is not taken for a locked one by a timeout alone
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = r.Exec(ctx, "AT+DEVEUI=?")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Exec on a locked module = %v, want a timeout", err)
+	}
+	if r.Locked() {
+		t.Error("a timeout marked the module locked")
+	}
+
+	locked, err = r.DetectLock(context.Background())
+	if err != nil || !locked {
+		t.Fatalf("DetectLock on a locked module = %v, %v", locked, err)
+	}
+	_, err = r.Exec(context.Background(), "AT+DEVEUI=?")
+	if !errors.Is(err, rui3.ErrLocked) {
+		t.Errorf("Exec after DetectLock = %v, want ErrLocked", err)
+	}
+
+	err = r.Unlock(context.Background(), "s3cret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Locked() {
+		t.Error("module still locked after Unlock")
+	}
+}
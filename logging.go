@@ -0,0 +1,29 @@
+package rui3
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// WithLogger logs every line sent and received, command latencies and
+// events at debug level. Keys are redacted. Nothing is logged by default
+// or with a nil logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		if logger == nil {
+			logger = slog.New(slog.DiscardHandler)
+		}
+		o.logger = logger
+	}
+}
+
+// redact hides the value of commands and responses that carry keys.
+func redact(line string) string {
+	for _, cmd := range secretCommands {
+		value, ok := strings.CutPrefix(line, cmd+"=")
+		if ok && value != "?" {
+			return cmd + "=" + redacted
+		}
+	}
+	return line
+}
@@ -0,0 +1,245 @@
+package rui3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrModuleUnavailable = errors.New("module unavailable")
+
+// ModuleEvent is an event of one of the modules owned by a Manager.
+type ModuleEvent struct {
+	Module string `json:"module"`
+	Event
+}
+
+// Manager owns several supervised modules, keyed by DevEUI. Each module is
+// used by one caller at a time and reopened on its own when its transport
+// fails.
+type Manager struct {
+	mu      sync.Mutex
+	modules map[string]*managedModule
+	events  chan ModuleEvent
+	logger  *slog.Logger
+	wg      sync.WaitGroup
+	closed  bool
+}
+
+type managedModule struct {
+	sup     *Supervisor
+	pending int
+	lastUse time.Time
+}
+
+func NewManager(logger *slog.Logger) *Manager {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	return &Manager{
+		modules: make(map[string]*managedModule),
+		events:  make(chan ModuleEvent, 64),
+		logger:  logger,
+	}
+}
+
+// Add opens a module on a port, see New, and returns its key.
+func (m *Manager) Add(ctx context.Context, portName string, opts ...Option) (string, error) {
+	return m.AddFunc(ctx, func(ctx context.Context) (*RUI3, error) {
+		return New(portName, opts...)
+	})
+}
+
+// AddFunc supervises the module opened by open, see NewSupervisor, and
+// returns the module's DevEUI, or its serial number when the DevEUI can
+// not be read.
+func (m *Manager) AddFunc(ctx context.Context, open OpenFunc) (string, error) {
+	sup, err := NewSupervisor(ctx, open, m.logger)
+	if err != nil {
+		return "", err
+	}
+	key := sup.DevEUI()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		sup.Close()
+		return "", errors.New("manager closed")
+	}
+	if _, ok := m.modules[key]; ok {
+		sup.Close()
+		return "", fmt.Errorf("module %s already added", key)
+	}
+
+	m.modules[key] = &managedModule{sup: sup}
+
+	events, _ := sup.Subscribe()
+	m.wg.Add(1)
+	go m.forward(key, events)
+
+	return key, nil
+}
+
+// Remove closes a module and stops reopening it.
+func (m *Manager) Remove(key string) error {
+	m.mu.Lock()
+	mod, ok := m.lookup(key)
+	if ok {
+		delete(m.modules, mod.sup.DevEUI())
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrModuleUnavailable, key)
+	}
+	return mod.sup.Close()
+}
+
+// lookup finds a module by DevEUI or serial number, called with m.mu held.
+func (m *Manager) lookup(key string) (*managedModule, bool) {
+	if mod, ok := m.modules[strings.ToUpper(key)]; ok {
+		return mod, true
+	}
+	for _, mod := range m.modules {
+		if serial := mod.sup.SerialNumber(); serial != "" && serial == key {
+			return mod, true
+		}
+	}
+	return nil, false
+}
+
+// Modules returns the keys of all modules, connected or not.
+func (m *Manager) Modules() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return slices.Sorted(maps.Keys(m.modules))
+}
+
+// State returns the connection state of a module.
+func (m *Manager) State(key string) (ConnState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mod, ok := m.lookup(key)
+	if !ok {
+		return StateDisconnected, fmt.Errorf("%w: %s", ErrModuleUnavailable, key)
+	}
+	return mod.sup.State(), nil
+}
+
+// Events returns the events and connection state changes of all modules.
+// Events are dropped when the channel is not drained.
+func (m *Manager) Events() <-chan ModuleEvent {
+	return m.events
+}
+
+// Do runs fn with exclusive use of a module. An empty key picks the least
+// busy connected module.
+func (m *Manager) Do(ctx context.Context, key string, fn func(key string, r *RUI3) error) error {
+	mod, err := m.acquire(key)
+	if err != nil {
+		return err
+	}
+	defer m.release(mod)
+
+	return mod.sup.Do(ctx, func(r *RUI3) error {
+		return fn(mod.sup.DevEUI(), r)
+	})
+}
+
+// Send sends an uplink through a module, or through the least busy one
+// when key is empty. It returns the key of the module that sent it.
+func (m *Manager) Send(ctx context.Context, key string, port int, payload []byte) (string, error) {
+	var sentBy string
+	err := m.Do(ctx, key, func(key string, r *RUI3) error {
+		sentBy = key
+		return r.SendOnPort(port, payload)
+	})
+	return sentBy, err
+}
+
+func (m *Manager) acquire(key string) (*managedModule, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var mod *managedModule
+	if key != "" {
+		var ok bool
+		mod, ok = m.lookup(key)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrModuleUnavailable, key)
+		}
+	} else {
+		mod = m.leastBusy()
+		if mod == nil {
+			return nil, fmt.Errorf("%w: no module connected", ErrModuleUnavailable)
+		}
+	}
+	mod.pending++
+	return mod, nil
+}
+
+func (m *Manager) release(mod *managedModule) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	mod.pending--
+	mod.lastUse = time.Now()
+}
+
+// leastBusy picks the connected module with the fewest callers, spreading
+// ties by last use. Called with m.mu held.
+func (m *Manager) leastBusy() *managedModule {
+	var best *managedModule
+	for _, mod := range m.modules {
+		if mod.sup.State() != StateConnected {
+			continue
+		}
+		if best == nil || mod.pending < best.pending ||
+			mod.pending == best.pending && mod.lastUse.Before(best.lastUse) {
+			best = mod
+		}
+	}
+	return best
+}
+
+// forward runs until the module is removed or the manager closed.
+func (m *Manager) forward(key string, events <-chan Event) {
+	defer m.wg.Done()
+
+	for ev := range events {
+		select {
+		case m.events <- ModuleEvent{Module: key, Event: ev}:
+		default:
+			m.logger.Debug("event dropped", "module", key, "event", ev.Name)
+		}
+	}
+}
+
+// Close closes every module. The events channel is closed once all
+// modules have stopped.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	modules := m.modules
+	m.modules = make(map[string]*managedModule)
+	m.mu.Unlock()
+
+	var errs []error
+	for _, mod := range modules {
+		errs = append(errs, mod.sup.Close())
+	}
+
+	m.wg.Wait()
+	close(m.events)
+	return errors.Join(errs...)
+}
@@ -0,0 +1,105 @@
+package rui3_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"tencorvids/rui3-go"
+	"tencorvids/rui3-go/sim"
+)
+
+// joinedSim returns a joined module with the given IDs.
+func joinedSim(devEUI, serial string) *sim.Module {
+	m := sim.New()
+	m.SetValue("AT+DEVEUI", devEUI)
+	m.SetValue("AT+SN", serial)
+	m.SetValue("AT+NJS", "1")
+	return m
+}
+
+func TestManagerModules(t *testing.T) {
+	mgr := rui3.NewManager(nil)
+	defer mgr.Close()
+	ctx := context.Background()
+
+	for _, m := range []*sim.Module{
+		joinedSim("AC1F09FFFE000002", "SN2"),
+		joinedSim("ac1f09fffe000001", "SN1"),
+	} {
+		_, err := mgr.AddFunc(ctx, openSim(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"AC1F09FFFE000001", "AC1F09FFFE000002"}
+	if got := mgr.Modules(); !slices.Equal(got, want) {
+		t.Errorf("Modules = %v, want %v", got, want)
+	}
+
+	_, err := mgr.AddFunc(ctx, openSim(joinedSim("AC1F09FFFE000001", "SN3")))
+	if err == nil {
+		t.Error("adding a module twice succeeded")
+	}
+
+	// modules are found by DevEUI in any case or by serial number
+	for _, key := range []string{"ac1f09fffe000002", "SN2"} {
+		err = mgr.Do(ctx, key, func(got string, r *rui3.RUI3) error {
+			if got != want[1] {
+				t.Errorf("Do(%q) ran on %s", key, got)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Errorf("Do(%q): %v", key, err)
+		}
+	}
+
+	sentBy, err := mgr.Send(ctx, "", 2, []byte{1, 2})
+	if err != nil || !slices.Contains(want, sentBy) {
+		t.Errorf("Send = %q, %v", sentBy, err)
+	}
+
+	err = mgr.Remove("SN1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mgr.Modules(); !slices.Equal(got, want[1:]) {
+		t.Errorf("Modules after Remove = %v, want %v", got, want[1:])
+	}
+	err = mgr.Do(ctx, want[0], func(string, *rui3.RUI3) error { return nil })
+	if !errors.Is(err, rui3.ErrModuleUnavailable) {
+		t.Errorf("Do on a removed module = %v, want ErrModuleUnavailable", err)
+	}
+}
+
+func TestManagerForwardsModuleEvents(t *testing.T) {
+	mgr := rui3.NewManager(nil)
+	ctx := context.Background()
+
+	m := sim.New()
+	key, err := mgr.AddFunc(ctx, openSim(m))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Write([]byte("ATZ\r\n"))
+	for ev := range mgr.Events() {
+		if ev.Name == rui3.StateConnected.String() {
+			if ev.Module != key {
+				t.Errorf("event of %q, want %q", ev.Module, key)
+			}
+			break
+		}
+	}
+
+	// closing the manager closes the events channel
+	err = mgr.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range mgr.Events() {
+	}
+}
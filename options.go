@@ -0,0 +1,111 @@
+package rui3
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+type Option func(*options)
+
+type options struct {
+	mode        serial.Mode
+	readTimeout time.Duration
+	dtr         *bool
+	rts         *bool
+	autoBaud    bool
+	logger      *slog.Logger
+	capture     io.Writer
+	password    Secret
+}
+
+func defaultOptions() options {
+	return options{
+		mode: serial.Mode{
+			BaudRate: 115200,
+			Parity:   serial.NoParity,
+			DataBits: 8,
+			StopBits: serial.OneStopBit,
+		},
+		logger: slog.New(slog.DiscardHandler),
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := defaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// serialMode returns the mode to open the port with, including the initial
+// DTR/RTS state when either was configured.
+func (o options) serialMode() *serial.Mode {
+	mode := o.mode
+	if o.dtr != nil || o.rts != nil {
+		bits := &serial.ModemOutputBits{DTR: true, RTS: true}
+		if o.dtr != nil {
+			bits.DTR = *o.dtr
+		}
+		if o.rts != nil {
+			bits.RTS = *o.rts
+		}
+		mode.InitialStatusBits = bits
+	}
+	return &mode
+}
+
+func WithBaudRate(baud int) Option {
+	return func(o *options) {
+		o.mode.BaudRate = baud
+	}
+}
+
+func WithParity(parity serial.Parity) Option {
+	return func(o *options) {
+		o.mode.Parity = parity
+	}
+}
+
+func WithDataBits(bits int) Option {
+	return func(o *options) {
+		o.mode.DataBits = bits
+	}
+}
+
+func WithStopBits(bits serial.StopBits) Option {
+	return func(o *options) {
+		o.mode.StopBits = bits
+	}
+}
+
+func WithReadTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.readTimeout = timeout
+	}
+}
+
+// WithDTR sets the DTR line when the port is opened. Some USB adapters
+// wire DTR/RTS to the module reset or boot pins.
+func WithDTR(dtr bool) Option {
+	return func(o *options) {
+		o.dtr = &dtr
+	}
+}
+
+func WithRTS(rts bool) Option {
+	return func(o *options) {
+		o.rts = &rts
+	}
+}
+
+// WithAutoBaud probes the common baud rates on New until the module
+// answers, starting with the configured one.
+func WithAutoBaud() Option {
+	return func(o *options) {
+		o.autoBaud = true
+	}
+}
@@ -0,0 +1,119 @@
+package rui3
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type P2PPacket struct {
+	RSSI    int    `json:"rssi"`
+	SNR     int    `json:"snr"`
+	Payload []byte `json:"payload"`
+}
+
+// SwitchNetworkMode changes the work mode with AT+NWM. The module restarts
+// to apply it, so this waits until it is ready again and returns the boot
+// message, like ResetMCU.
+func (r *RUI3) SwitchNetworkMode(ctx context.Context, mode NetworkMode) (string, error) {
+	if mode < NetworkModeP2P || mode > NetworkModeFSK {
+		return "", fmt.Errorf("invalid network mode: %d", mode)
+	}
+
+	err := r.SendRawCommand("AT+NWM=" + strconv.Itoa(int(mode)))
+	if err != nil {
+		return "", fmt.Errorf("failed to send AT+NWM command: %w", err)
+	}
+
+	return r.waitReady(ctx)
+}
+
+// SendP2P transmits a raw LoRa packet. The module has to be in P2P mode,
+// see SwitchNetworkMode.
+func (r *RUI3) SendP2P(payload []byte) error {
+	if len(payload) == 0 || len(payload) > 255 {
+		return fmt.Errorf("invalid payload length: %d", len(payload))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	response, err := r.Exec(ctx, "AT+PSEND="+hex.EncodeToString(payload))
+	if err != nil {
+		return err
+	}
+
+	if strings.Contains(response, "OK") {
+		return nil
+	}
+
+	return fmt.Errorf("failed to send p2p payload: %s", response)
+}
+
+// ReceiveP2P opens the receive window for up to window and returns the
+// first packet, or the context error when none arrives.
+func (r *RUI3) ReceiveP2P(ctx context.Context, window time.Duration) (P2PPacket, error) {
+	if window < time.Millisecond || window > 65533*time.Millisecond {
+		return P2PPacket{}, fmt.Errorf("invalid receive window: %v", window)
+	}
+
+	events, unsubscribe := r.Subscribe()
+	defer unsubscribe()
+
+	err := r.Set(ctx, "AT+PRECV", window.Milliseconds())
+	if err != nil {
+		return P2PPacket{}, err
+	}
+
+	timeout := time.After(window)
+	for {
+		select {
+		case <-ctx.Done():
+			r.stopReceiveP2P()
+			return P2PPacket{}, ctx.Err()
+		case <-timeout:
+			return P2PPacket{}, errors.New("no p2p packet received")
+		case ev, ok := <-events:
+			if !ok {
+				return P2PPacket{}, fmt.Errorf("failed to receive p2p packet: %w", r.readErr)
+			}
+			if packet, ok := parseP2PPacket(ev); ok {
+				return packet, nil
+			}
+		}
+	}
+}
+
+func (r *RUI3) stopReceiveP2P() {
+	ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+	defer cancel()
+	r.Set(ctx, "AT+PRECV", 0)
+}
+
+// parseP2PPacket decodes "+EVT:RXP2P:-60:9:AABB".
+func parseP2PPacket(ev Event) (P2PPacket, bool) {
+	if ev.Name != "RXP2P" || len(ev.Params) != 3 {
+		return P2PPacket{}, false
+	}
+
+	var packet P2PPacket
+	var err error
+	packet.RSSI, err = strconv.Atoi(ev.Params[0])
+	if err != nil {
+		return packet, false
+	}
+	packet.SNR, err = strconv.Atoi(ev.Params[1])
+	if err != nil {
+		return packet, false
+	}
+	packet.Payload, err = hex.DecodeString(ev.Params[2])
+	if err != nil {
+		return packet, false
+	}
+
+	return packet, true
+}
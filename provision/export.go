@@ -0,0 +1,305 @@
+package provision
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"tencorvids/rui3-go"
+)
+
+// RUI3 implements LoRaWAN 1.0.3 with the revision A regional parameters.
+const (
+	ttsMACVersion      = "MAC_V1_0_3"
+	ttsPHYVersion      = "PHY_V1_0_3_REV_A"
+	chirpStackMAC      = "LORAWAN_1_0_3"
+	chirpStackRevision = "A"
+	deviceIDPrefix     = "eui-"
+)
+
+// ttsFrequencyPlans maps bands to The Things Stack frequency plan IDs. Bands
+// without an unambiguous plan are rejected rather than guessed.
+var ttsFrequencyPlans = map[rui3.RegionBand]string{
+	rui3.EU433: "EU_433",
+	rui3.CN470: "CN_470_510_FSB_11",
+	rui3.RU864: "RU_864_870_TTN",
+	rui3.IN865: "IN_865_867",
+	rui3.EU868: "EU_863_870_TTN",
+	rui3.KR920: "KR_920_923_TTN",
+	rui3.AS923: "AS_920_923",
+}
+
+// ttsSubBandPlans holds the plan ID prefixes of bands with one plan per
+// sub-band, chosen from the recorded channel mask.
+var ttsSubBandPlans = map[rui3.RegionBand]string{
+	rui3.US915: "US_902_928_FSB_",
+	rui3.AU915: "AU_915_928_FSB_",
+}
+
+var chirpStackRegions = map[rui3.RegionBand]string{
+	rui3.EU433:   "EU433",
+	rui3.CN470:   "CN470",
+	rui3.RU864:   "RU864",
+	rui3.IN865:   "IN865",
+	rui3.EU868:   "EU868",
+	rui3.US915:   "US915",
+	rui3.AU915:   "AU915",
+	rui3.KR920:   "KR920",
+	rui3.AS923:   "AS923",
+	rui3.AS923_2: "AS923_2",
+	rui3.AS923_3: "AS923_3",
+	rui3.AS923_4: "AS923_4",
+}
+
+// ReadRecords reads the output of a CSV or JSON sink.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	br := bufio.NewReader(r)
+	first, err := br.Peek(1)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(first, []byte("{")) {
+		return readJSONRecords(br)
+	}
+	return readCSVRecords(br)
+}
+
+func readJSONRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	dec := json.NewDecoder(r)
+	for {
+		var rec Record
+		err := dec.Decode(&rec)
+		if errors.Is(err, io.EOF) {
+			return records, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid record %d: %w", len(records)+1, err)
+		}
+		records = append(records, rec)
+	}
+}
+
+func readCSVRecords(r io.Reader) ([]Record, error) {
+	cr := csv.NewReader(r)
+	// rows appended to a file started before the channel_mask column carry
+	// one field more than its header
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[name] = i
+	}
+	for _, name := range csvHeader {
+		if _, ok := columns[name]; !ok && name != csvChannelMask {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	records := make([]Record, 0, len(rows)-1)
+	for i, row := range rows[1:] {
+		if len(row) < len(rows[0]) {
+			return nil, fmt.Errorf("row %d has %d fields, want %d", i+2, len(row), len(rows[0]))
+		}
+		rec := Record{
+			DevEUI:       row[columns["dev_eui"]],
+			JoinEUI:      row[columns["join_eui"]],
+			AppKey:       rui3.Secret(row[columns["app_key"]]),
+			SerialNumber: row[columns["serial_number"]],
+		}
+		rec.Time, err = time.Parse(time.RFC3339, row[columns["time"]])
+		if err != nil {
+			return nil, fmt.Errorf("invalid time in row %d: %w", i+2, err)
+		}
+		err = rec.Band.UnmarshalText([]byte(row[columns["band"]]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid band in row %d: %w", i+2, err)
+		}
+		if column, ok := columns[csvChannelMask]; ok {
+			rec.ChannelMask = row[column]
+		}
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// FillKeys takes the AppKey of records written without RevealKeys from
+// the key store the provisioner saved them to.
+func FillKeys(records []Record, store rui3.KeyStore) error {
+	for i := range records {
+		rec := &records[i]
+		if !isRedacted(rec.AppKey) {
+			continue
+		}
+
+		keys, err := store.Load(rec.DevEUI)
+		if err != nil {
+			return err
+		}
+		rec.AppKey = keys.AppKey
+		if rec.JoinEUI == "" {
+			rec.JoinEUI = keys.JoinEUI
+		}
+	}
+	return nil
+}
+
+// isRedacted reports whether a key was read back from a redacted record,
+// whose value is the redaction itself.
+func isRedacted(key rui3.Secret) bool {
+	return key == "" || key.Reveal() == key.String()
+}
+
+func appKey(rec Record) (string, error) {
+	if isRedacted(rec.AppKey) {
+		return "", fmt.Errorf("%s: AppKey is redacted, fill it from a key store", rec.DevEUI)
+	}
+	return rec.AppKey.Reveal(), nil
+}
+
+func ttsFrequencyPlan(rec Record) (string, error) {
+	prefix, ok := ttsSubBandPlans[rec.Band]
+	if !ok {
+		plan, ok := ttsFrequencyPlans[rec.Band]
+		if !ok {
+			return "", fmt.Errorf("%s: no The Things Stack frequency plan for %s", rec.DevEUI, rec.Band)
+		}
+		return plan, nil
+	}
+
+	subBand, err := singleSubBand(rec.ChannelMask)
+	if err != nil {
+		return "", fmt.Errorf("%s: no The Things Stack frequency plan for %s: %w", rec.DevEUI, rec.Band, err)
+	}
+	return prefix + strconv.Itoa(subBand), nil
+}
+
+// singleSubBand returns the sub-band enabled by a channel mask, bit 0 being
+// sub-band 1. Masks enabling several sub-bands have no plan.
+func singleSubBand(mask string) (int, error) {
+	if mask == "" {
+		return 0, errors.New("channel mask not recorded")
+	}
+	value, err := strconv.ParseUint(mask, 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid channel mask %q", mask)
+	}
+	if value == 0 || value > 0xFF || value&(value-1) != 0 {
+		return 0, fmt.Errorf("channel mask %s does not select a single sub-band", mask)
+	}
+	return bits.TrailingZeros64(value) + 1, nil
+}
+
+func deviceID(rec Record) string {
+	return deviceIDPrefix + strings.ToLower(rec.DevEUI)
+}
+
+type ttsKey struct {
+	Key string `json:"key"`
+}
+
+type ttsDevice struct {
+	IDs struct {
+		DeviceID string `json:"device_id"`
+		DevEUI   string `json:"dev_eui"`
+		JoinEUI  string `json:"join_eui"`
+	} `json:"ids"`
+	Name              string `json:"name"`
+	LoRaWANVersion    string `json:"lorawan_version"`
+	LoRaWANPHYVersion string `json:"lorawan_phy_version"`
+	FrequencyPlanID   string `json:"frequency_plan_id"`
+	SupportsJoin      bool   `json:"supports_join"`
+	RootKeys          struct {
+		AppKey ttsKey `json:"app_key"`
+	} `json:"root_keys"`
+}
+
+// WriteTTS writes one device per line in The Things Stack JSON format, as
+// read by "ttn-lw-cli end-devices create" and the console import.
+func WriteTTS(w io.Writer, records []Record) error {
+	enc := json.NewEncoder(w)
+	for _, rec := range records {
+		plan, err := ttsFrequencyPlan(rec)
+		if err != nil {
+			return err
+		}
+		key, err := appKey(rec)
+		if err != nil {
+			return err
+		}
+
+		var dev ttsDevice
+		dev.IDs.DeviceID = deviceID(rec)
+		dev.IDs.DevEUI = rec.DevEUI
+		dev.IDs.JoinEUI = rec.JoinEUI
+		dev.Name = dev.IDs.DeviceID
+		dev.LoRaWANVersion = ttsMACVersion
+		dev.LoRaWANPHYVersion = ttsPHYVersion
+		dev.FrequencyPlanID = plan
+		dev.SupportsJoin = true
+		dev.RootKeys.AppKey.Key = key
+
+		err = enc.Encode(dev)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteChirpStack writes a ChirpStack device import CSV. LoRaWAN 1.0.x
+// devices keep the AppKey in the nwk_key column.
+func WriteChirpStack(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"name", "dev_eui", "join_eui", "nwk_key", "mac_version", "regional_parameters_revision", "region"})
+	if err != nil {
+		return err
+	}
+
+	for _, rec := range records {
+		region, ok := chirpStackRegions[rec.Band]
+		if !ok {
+			return fmt.Errorf("%s: no ChirpStack region for %s", rec.DevEUI, rec.Band)
+		}
+		key, err := appKey(rec)
+		if err != nil {
+			return err
+		}
+
+		err = cw.Write([]string{
+			deviceID(rec),
+			strings.ToLower(rec.DevEUI),
+			strings.ToLower(rec.JoinEUI),
+			strings.ToLower(key),
+			chirpStackMAC,
+			chirpStackRevision,
+			region,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
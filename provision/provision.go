@@ -0,0 +1,188 @@
+// Package provision programs fresh OTAA keys into modules and records them
+// for registration on a network server.
+package provision
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"tencorvids/rui3-go"
+)
+
+// ErrVerify is returned when a key read back from the module differs from
+// the one written.
+var ErrVerify = errors.New("key verification failed")
+
+// Record describes one provisioned module. The AppKey is redacted when the
+// record is printed or encoded, see RevealKeys. ChannelMask is only read on
+// bands whose frequency plan depends on the sub-band, US915 and AU915.
+type Record struct {
+	Time         time.Time       `json:"time"`
+	DevEUI       string          `json:"devEUI"`
+	JoinEUI      string          `json:"joinEUI"`
+	AppKey       rui3.Secret     `json:"appKey"`
+	Band         rui3.RegionBand `json:"band"`
+	ChannelMask  string          `json:"channelMask,omitempty"`
+	SerialNumber string          `json:"serialNumber"`
+}
+
+type Option func(*Provisioner)
+
+// WithJoinEUI writes the given JoinEUI to every module.
+func WithJoinEUI(joinEUI string) Option {
+	return func(p *Provisioner) {
+		p.joinEUI = strings.ToUpper(joinEUI)
+	}
+}
+
+// WithRandomJoinEUI generates a JoinEUI per module. Without it or
+// WithJoinEUI the module keeps its current JoinEUI.
+func WithRandomJoinEUI() Option {
+	return func(p *Provisioner) {
+		p.randomJoinEUI = true
+	}
+}
+
+// WithRandom replaces crypto/rand as the key source.
+func WithRandom(random io.Reader) Option {
+	return func(p *Provisioner) {
+		p.random = random
+	}
+}
+
+// WithKeyStore also saves the keys to store, so gateways can program them
+// later with RUI3.LoadKeys.
+func WithKeyStore(store rui3.KeyStore) Option {
+	return func(p *Provisioner) {
+		p.store = store
+	}
+}
+
+// Provisioner generates keys, writes them and hands the result to a sink.
+// It can be reused for any number of modules.
+type Provisioner struct {
+	sink          Sink
+	joinEUI       string
+	randomJoinEUI bool
+	random        io.Reader
+	store         rui3.KeyStore
+}
+
+func New(sink Sink, opts ...Option) *Provisioner {
+	p := &Provisioner{sink: sink, random: rand.Reader}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Provision reads the DevEUI, writes a new AppKey and the JoinEUI, verifies
+// both by reading them back and writes the record to the sink. The record
+// is only emitted once the module holds the keys.
+func (p *Provisioner) Provision(ctx context.Context, r *rui3.RUI3) (Record, error) {
+	rec := Record{Time: time.Now().UTC()}
+	var err error
+
+	rec.DevEUI, err = rui3.Query(ctx, r, "AT+DEVEUI", rui3.ParseString)
+	if err != nil {
+		return rec, fmt.Errorf("failed to read DevEUI: %w", err)
+	}
+	rec.SerialNumber, err = rui3.Query(ctx, r, "AT+SN", rui3.ParseString)
+	if err != nil {
+		return rec, fmt.Errorf("failed to read serial number: %w", err)
+	}
+	rec.Band, err = r.GetRegionBand()
+	if err != nil {
+		return rec, fmt.Errorf("failed to read region band: %w", err)
+	}
+	if _, ok := ttsSubBandPlans[rec.Band]; ok {
+		mask, err := rui3.Query(ctx, r, "AT+MASK", rui3.ParseString)
+		if err != nil {
+			return rec, fmt.Errorf("failed to read channel mask: %w", err)
+		}
+		rec.ChannelMask = strings.ToUpper(mask)
+	}
+
+	appKey, err := p.generate(16)
+	if err != nil {
+		return rec, err
+	}
+	rec.AppKey = rui3.Secret(appKey)
+
+	switch {
+	case p.joinEUI != "":
+		rec.JoinEUI = p.joinEUI
+	case p.randomJoinEUI:
+		rec.JoinEUI, err = p.generate(8)
+		if err != nil {
+			return rec, err
+		}
+	default:
+		rec.JoinEUI, err = rui3.Query(ctx, r, "AT+APPEUI", rui3.ParseString)
+		if err != nil {
+			return rec, fmt.Errorf("failed to read JoinEUI: %w", err)
+		}
+	}
+
+	err = p.write(ctx, r, "AT+APPEUI", rec.JoinEUI, 8)
+	if err != nil {
+		return rec, err
+	}
+	err = p.write(ctx, r, "AT+APPKEY", rec.AppKey.Reveal(), 16)
+	if err != nil {
+		return rec, err
+	}
+
+	if p.store != nil {
+		err = p.store.Save(rec.DevEUI, rui3.DeviceKeys{JoinEUI: rec.JoinEUI, AppKey: rec.AppKey})
+		if err != nil {
+			return rec, fmt.Errorf("failed to save keys: %w", err)
+		}
+	}
+
+	err = p.sink.Write(rec)
+	if err != nil {
+		return rec, fmt.Errorf("failed to write record: %w", err)
+	}
+
+	return rec, nil
+}
+
+func (p *Provisioner) generate(length int) (string, error) {
+	key := make([]byte, length)
+	_, err := io.ReadFull(p.random, key)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return strings.ToUpper(hex.EncodeToString(key)), nil
+}
+
+func (p *Provisioner) write(ctx context.Context, r *rui3.RUI3, cmd, value string, length int) error {
+	name := strings.TrimPrefix(cmd, "AT+")
+
+	key, err := hex.DecodeString(value)
+	if err != nil || len(key) != length {
+		return fmt.Errorf("invalid %s: %q", name, value)
+	}
+
+	err = r.Set(ctx, cmd, key)
+	if err != nil {
+		return err
+	}
+
+	stored, err := rui3.Query(ctx, r, cmd, rui3.ParseString)
+	if err != nil {
+		return fmt.Errorf("failed to read back %s: %w", name, err)
+	}
+	if !strings.EqualFold(stored, value) {
+		return fmt.Errorf("%w: %s", ErrVerify, name)
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package provision
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sink stores provisioning records. Implementations are safe for use by
+// several provisioning stations at once.
+type Sink interface {
+	Write(rec Record) error
+}
+
+type SinkOption func(*sinkOptions)
+
+type sinkOptions struct {
+	reveal bool
+}
+
+// RevealKeys writes the AppKey in plain text. Without it records carry a
+// redacted AppKey and export needs the keys from a key store, see FillKeys.
+func RevealKeys() SinkOption {
+	return func(o *sinkOptions) {
+		o.reveal = true
+	}
+}
+
+func newSinkOptions(opts []SinkOption) sinkOptions {
+	var o sinkOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// csvChannelMask was added after the other columns, files without it are
+// still read.
+const csvChannelMask = "channel_mask"
+
+var csvHeader = []string{"time", "dev_eui", "join_eui", "app_key", "band", "serial_number", csvChannelMask}
+
+type CSVSink struct {
+	mu     sync.Mutex
+	w      *csv.Writer
+	header bool
+	reveal bool
+}
+
+// NewCSVSink writes a header row before the first record.
+func NewCSVSink(w io.Writer, opts ...SinkOption) *CSVSink {
+	return &CSVSink{w: csv.NewWriter(w), header: true, reveal: newSinkOptions(opts).reveal}
+}
+
+func (s *CSVSink) Write(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.header {
+		err := s.w.Write(csvHeader)
+		if err != nil {
+			return err
+		}
+		s.header = false
+	}
+
+	appKey := rec.AppKey.String()
+	if s.reveal {
+		appKey = rec.AppKey.Reveal()
+	}
+
+	err := s.w.Write([]string{
+		rec.Time.Format(time.RFC3339),
+		rec.DevEUI,
+		rec.JoinEUI,
+		appKey,
+		rec.Band.String(),
+		rec.SerialNumber,
+		rec.ChannelMask,
+	})
+	if err != nil {
+		return err
+	}
+
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// JSONSink writes one JSON object per line.
+type JSONSink struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	reveal bool
+}
+
+func NewJSONSink(w io.Writer, opts ...SinkOption) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w), reveal: newSinkOptions(opts).reveal}
+}
+
+// revealedRecord shadows the redacted AppKey of Record.
+type revealedRecord struct {
+	Record
+	AppKey string `json:"appKey"`
+}
+
+func (s *JSONSink) Write(rec Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reveal {
+		return s.enc.Encode(revealedRecord{Record: rec, AppKey: rec.AppKey.Reveal()})
+	}
+	return s.enc.Encode(rec)
+}
+
+// FileSink appends records to a file.
+type FileSink struct {
+	Sink
+	f *os.File
+}
+
+// OpenFile appends to a .csv or a .json/.jsonl file, creating it if needed.
+// The CSV header is only written to new files.
+func OpenFile(path string, opts ...SinkOption) (*FileSink, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext != ".csv" && ext != ".json" && ext != ".jsonl" {
+		return nil, fmt.Errorf("unknown record format %q", ext)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+
+	if ext != ".csv" {
+		return &FileSink{Sink: NewJSONSink(f, opts...), f: f}, nil
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	sink := NewCSVSink(f, opts...)
+	sink.header = info.Size() == 0
+	return &FileSink{Sink: sink, f: f}, nil
+}
+
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,79 @@
+package rui3
+
+import (
+	"context"
+	"fmt"
+)
+
+// RawPort gives a function passed to Raw direct access to the transport.
+type RawPort struct {
+	r    *RUI3
+	data chan []byte
+	done chan struct{}
+	buf  []byte
+}
+
+// Raw hands the transport to fn for a binary protocol such as the DFU
+// bootloader. Data read meanwhile is passed to the RawPort instead of being
+// parsed as lines, and no commands may be sent until fn returns.
+func (r *RUI3) Raw(fn func(p *RawPort) error) error {
+	r.ResetInputBuffer()
+
+	p := &RawPort{r: r, data: make(chan []byte, 64), done: make(chan struct{})}
+	r.raw.Store(p)
+	defer func() {
+		r.raw.Store(nil)
+		close(p.done)
+	}()
+
+	return fn(p)
+}
+
+// deliver is called by readLoop and blocks until the data is read or Raw
+// has returned.
+func (p *RawPort) deliver(data []byte) {
+	select {
+	case p.data <- data:
+	case <-p.done:
+	}
+}
+
+func (p *RawPort) Write(b []byte) (int, error) {
+	n, err := p.r.writer.Write(b)
+	if err != nil {
+		return n, err
+	}
+	return n, p.r.writer.Flush()
+}
+
+// ReadContext reads what the module sent, waiting until data arrives or ctx
+// is done.
+func (p *RawPort) ReadContext(ctx context.Context, b []byte) (int, error) {
+	if len(p.buf) == 0 {
+		select {
+		case p.buf = <-p.data:
+		case <-p.r.readDone:
+			return 0, fmt.Errorf("failed to read: %w", p.r.readErr)
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	n := copy(b, p.buf)
+	p.buf = p.buf[n:]
+	return n, nil
+}
+
+// ExpectRestart announces that the module restarts once the protocol ends,
+// so its boot message is not reported as EventRestarted. Call WaitReady
+// after Raw has returned.
+func (p *RawPort) ExpectRestart() {
+	p.r.expectBoot.Store(true)
+}
+
+// WaitReady waits until a restarting module has printed its boot message
+// or answers AT again, see ResetMCU. Without a deadline on ctx it waits up
+// to 15 seconds.
+func (r *RUI3) WaitReady(ctx context.Context) (string, error) {
+	return r.waitReady(ctx)
+}
@@ -0,0 +1,137 @@
+package rui3_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"tencorvids/rui3-go"
+)
+
+type chunk struct {
+	data []byte
+	err  error
+}
+
+// chunkTransport returns one queued chunk per Read, so tests decide where
+// reads split the stream.
+type chunkTransport struct {
+	reads  chan chunk
+	closed chan struct{}
+}
+
+func newChunkTransport() *chunkTransport {
+	return &chunkTransport{reads: make(chan chunk), closed: make(chan struct{})}
+}
+
+// feed hands data to the next Read. The reads channel is unbuffered, so
+// once a later feed returns the reader has handled this one.
+func (t *chunkTransport) feed(data string, err error) {
+	select {
+	case t.reads <- chunk{data: []byte(data), err: err}:
+	case <-t.closed:
+	}
+}
+
+// sync waits until everything fed before has been handled.
+func (t *chunkTransport) sync() {
+	t.feed("", nil)
+}
+
+func (t *chunkTransport) Read(p []byte) (int, error) {
+	select {
+	case c := <-t.reads:
+		return copy(p, c.data), c.err
+	case <-t.closed:
+		return 0, io.EOF
+	}
+}
+
+func (t *chunkTransport) Write(p []byte) (int, error)                { return len(p), nil }
+func (t *chunkTransport) SetReadTimeout(timeout time.Duration) error { return nil }
+func (t *chunkTransport) ResetInputBuffer() error                    { return nil }
+func (t *chunkTransport) ResetOutputBuffer() error                   { return nil }
+func (t *chunkTransport) Drain() error                               { return nil }
+
+func (t *chunkTransport) Close() error {
+	close(t.closed)
+	return nil
+}
+
+func TestReadLoopJoinsLinesSplitAcrossReads(t *testing.T) {
+	port := newChunkTransport()
+	r := rui3.NewWithPort(port)
+	defer r.Close()
+
+	port.feed("AT+DEVEUI=AC1F", nil)
+	port.feed("09FFFE000001\r", nil)
+	port.feed("\nOK\r\n", nil)
+
+	response, err := r.RecvResponse(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "AT+DEVEUI=AC1F09FFFE000001\nOK\n"; response != want {
+		t.Errorf("response = %q, want %q", response, want)
+	}
+}
+
+func TestReadLoopKeepsDataReadWithError(t *testing.T) {
+	port := newChunkTransport()
+	r := rui3.NewWithPort(port)
+	defer r.Close()
+
+	port.feed("OK\r\n", io.ErrUnexpectedEOF)
+
+	response, err := r.RecvResponse(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "OK\n" {
+		t.Errorf("response = %q, want the line read with the error", response)
+	}
+
+	_, err = r.RecvResponse(time.Second)
+	if err == nil {
+		t.Error("RecvResponse after the transport failed succeeded")
+	}
+}
+
+func TestRawDropsPartialLine(t *testing.T) {
+	port := newChunkTransport()
+	r := rui3.NewWithPort(port)
+	defer r.Close()
+
+	port.feed("+EVT:partial", nil)
+	port.sync()
+
+	err := r.Raw(func(p *rui3.RawPort) error {
+		go port.feed("\x01\xC0\n", nil)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		got := make([]byte, 8)
+		n, err := p.ReadContext(ctx, got)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(got[:n], []byte("\x01\xC0\n")) {
+			t.Errorf("raw read %q", got[:n])
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port.feed("OK\r\n", nil)
+	response, err := r.RecvResponse(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if response != "OK\n" {
+		t.Errorf("response after Raw = %q, want the partial line dropped", response)
+	}
+}
@@ -0,0 +1,63 @@
+package rui3_test
+
+import (
+	"testing"
+	"time"
+
+	"tencorvids/rui3-go"
+	"tencorvids/rui3-go/sim"
+)
+
+// restarted reports whether EventRestarted arrives within wait.
+func restarted(events <-chan rui3.Event, wait time.Duration) bool {
+	timeout := time.After(wait)
+	for {
+		select {
+		case ev := <-events:
+			if ev.Name == rui3.EventRestarted {
+				return true
+			}
+		case <-timeout:
+			return false
+		}
+	}
+}
+
+func TestNetworkModeChangeIsNotReportedAsRestart(t *testing.T) {
+	r := rui3.NewWithPort(sim.New())
+	defer r.Close()
+	events, cancel := r.Subscribe()
+	defer cancel()
+
+	err := r.SetNetworkMode(rui3.NetworkModeP2P)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restarted(events, 200*time.Millisecond) {
+		t.Error("the requested restart was reported as EventRestarted")
+	}
+
+	mode, err := r.GetNetworkMode()
+	if err != nil || mode != rui3.NetworkModeP2P {
+		t.Errorf("GetNetworkMode = %v, %v, want P2P", mode, err)
+	}
+}
+
+func TestSameNetworkModeKeepsRestartDetection(t *testing.T) {
+	m := sim.New()
+	r := rui3.NewWithPort(m)
+	defer r.Close()
+	events, cancel := r.Subscribe()
+	defer cancel()
+
+	// the module is in LoRaWAN mode already and does not restart
+	err := r.SetNetworkMode(rui3.NetworkModeLoRaWAN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m.Emit("RAKwireless RAK3172 Example", "Current Work Mode: LoRaWAN.")
+	if !restarted(events, time.Second) {
+		t.Error("a brown-out after a no-op mode change was not reported")
+	}
+}
@@ -0,0 +1,245 @@
+package rfc2217
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+const ackTimeout = 5 * time.Second
+
+// Conn is a serial port on an RFC 2217 server. It satisfies the transport
+// interface of the rui3 package.
+type Conn struct {
+	conn net.Conn
+
+	writeMu sync.Mutex
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	buf         bytes.Buffer
+	err         error
+	readTimeout time.Duration
+
+	acks chan []byte
+}
+
+// Dial connects to an RFC 2217 server and configures the remote port.
+func Dial(address string, mode *serial.Mode) (*Conn, error) {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", address, err)
+	}
+
+	c := NewConn(conn)
+
+	err = c.SetMode(mode)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// NewConn starts the COM port option negotiation on an established
+// connection.
+func NewConn(conn net.Conn) *Conn {
+	c := &Conn{
+		conn: conn,
+		acks: make(chan []byte, 16),
+	}
+	c.cond = sync.NewCond(&c.mu)
+
+	go c.readLoop()
+
+	c.send([]byte{
+		iac, will, optComPort,
+		iac, will, optBinary,
+		iac, do, optBinary,
+		iac, do, optSGA,
+	})
+
+	return c
+}
+
+func (c *Conn) readLoop() {
+	var acks [][]byte
+	d := &decoder{
+		onVerb: c.negotiate,
+		onSub: func(sub []byte) {
+			if len(sub) > 1 && sub[0] == optComPort && sub[1] >= serverOffset {
+				acks = append(acks, sub[1:])
+			}
+		},
+	}
+
+	raw := make([]byte, 1024)
+	var data []byte
+	for {
+		n, err := c.conn.Read(raw)
+		data = d.decode(raw[:n], data[:0])
+
+		c.mu.Lock()
+		c.buf.Write(data)
+		if err != nil {
+			c.err = err
+		}
+		c.cond.Broadcast()
+		c.mu.Unlock()
+
+		// acks are delivered after the data preceding them is buffered so
+		// a purge confirmation never races with stale data
+		for _, ack := range acks {
+			select {
+			case c.acks <- ack:
+			default:
+			}
+		}
+		acks = acks[:0]
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *Conn) negotiate(verb, opt byte) {
+	switch verb {
+	case do:
+		if opt != optComPort && opt != optBinary && opt != optSGA {
+			c.send([]byte{iac, wont, opt})
+		}
+	case will:
+		if opt != optBinary && opt != optSGA && opt != optComPort {
+			c.send([]byte{iac, dont, opt})
+		}
+	}
+}
+
+func (c *Conn) send(p []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err := c.conn.Write(p)
+	return err
+}
+
+// command sends a COM port command and waits for the server to confirm it.
+func (c *Conn) command(cmd byte, value []byte) error {
+	err := c.send(subnegotiation(cmd, value))
+	if err != nil {
+		return fmt.Errorf("failed to send com port command %d: %w", cmd, err)
+	}
+
+	timeout := time.After(ackTimeout)
+	for {
+		select {
+		case ack := <-c.acks:
+			if ack[0] == cmd+serverOffset {
+				return nil
+			}
+		case <-timeout:
+			return fmt.Errorf("no response to com port command %d", cmd)
+		}
+	}
+}
+
+func (c *Conn) SetMode(mode *serial.Mode) error {
+	dataBits := mode.DataBits
+	if dataBits == 0 {
+		dataBits = 8
+	}
+
+	err := c.command(setBaudRate, baudRateValue(mode.BaudRate))
+	if err != nil {
+		return err
+	}
+
+	err = c.command(setDataSize, []byte{byte(dataBits)})
+	if err != nil {
+		return err
+	}
+
+	err = c.command(setParity, []byte{parityValue(mode.Parity)})
+	if err != nil {
+		return err
+	}
+
+	return c.command(setStopSize, []byte{stopBitsValue(mode.StopBits)})
+}
+
+func (c *Conn) Read(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var deadline time.Time
+	if c.readTimeout > 0 {
+		deadline = time.Now().Add(c.readTimeout)
+		timer := time.AfterFunc(c.readTimeout, func() {
+			c.mu.Lock()
+			c.cond.Broadcast()
+			c.mu.Unlock()
+		})
+		defer timer.Stop()
+	}
+
+	for c.buf.Len() == 0 && c.err == nil {
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			// same as a local serial port, a timeout returns no data
+			return 0, nil
+		}
+		c.cond.Wait()
+	}
+
+	if c.buf.Len() > 0 {
+		return c.buf.Read(p)
+	}
+
+	if c.err == io.EOF {
+		return 0, io.EOF
+	}
+	return 0, c.err
+}
+
+func (c *Conn) Write(p []byte) (int, error) {
+	err := c.send(escape(p))
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) SetReadTimeout(timeout time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readTimeout = timeout
+	return nil
+}
+
+func (c *Conn) ResetInputBuffer() error {
+	err := c.command(purgeData, []byte{purgeReceive})
+
+	c.mu.Lock()
+	c.buf.Reset()
+	c.mu.Unlock()
+
+	return err
+}
+
+func (c *Conn) ResetOutputBuffer() error {
+	return c.command(purgeData, []byte{purgeTransmit})
+}
+
+// Drain is a no-op, the server forwards data as it arrives.
+func (c *Conn) Drain() error {
+	return nil
+}
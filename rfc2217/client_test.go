@@ -0,0 +1,206 @@
+package rfc2217
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+// fakePort records what the server does to it. Data written to out is
+// read by the server and forwarded to the client.
+type fakePort struct {
+	mu       sync.Mutex
+	mode     serial.Mode
+	purgedRx int
+	purgedTx int
+	received bytes.Buffer
+
+	in  *io.PipeReader
+	out *io.PipeWriter
+}
+
+func newFakePort() *fakePort {
+	in, out := io.Pipe()
+	return &fakePort{in: in, out: out}
+}
+
+func (p *fakePort) Read(b []byte) (int, error) {
+	return p.in.Read(b)
+}
+
+func (p *fakePort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.received.Write(b)
+}
+
+func (p *fakePort) SetMode(mode *serial.Mode) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.mode = *mode
+	return nil
+}
+
+func (p *fakePort) ResetInputBuffer() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.purgedRx++
+	return nil
+}
+
+func (p *fakePort) ResetOutputBuffer() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.purgedTx++
+	return nil
+}
+
+func startServer(t *testing.T) (*Server, *fakePort, string) {
+	t.Helper()
+
+	port := newFakePort()
+	server := NewServer(port)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+
+	t.Cleanup(func() {
+		ln.Close()
+		port.out.Close()
+	})
+
+	return server, port, ln.Addr().String()
+}
+
+func dial(t *testing.T, address string, mode *serial.Mode) *Conn {
+	t.Helper()
+
+	c, err := Dial(address, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	return c
+}
+
+// eventually polls cond, the server applies requests asynchronously.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestDialNegotiatesMode(t *testing.T) {
+	server, port, address := startServer(t)
+
+	want := serial.Mode{
+		BaudRate: 9600,
+		DataBits: 7,
+		Parity:   serial.EvenParity,
+		StopBits: serial.TwoStopBits,
+	}
+	dial(t, address, &want)
+
+	if got := server.Mode(); got != want {
+		t.Errorf("server mode = %+v, want %+v", got, want)
+	}
+
+	port.mu.Lock()
+	defer port.mu.Unlock()
+	if port.mode != want {
+		t.Errorf("port mode = %+v, want %+v", port.mode, want)
+	}
+}
+
+func TestSetModeChangesLineSettings(t *testing.T) {
+	server, _, address := startServer(t)
+	c := dial(t, address, &serial.Mode{BaudRate: 115200})
+
+	for _, parity := range []serial.Parity{serial.NoParity, serial.OddParity, serial.EvenParity, serial.MarkParity, serial.SpaceParity} {
+		mode := serial.Mode{BaudRate: 57600, DataBits: 8, Parity: parity, StopBits: serial.OneStopBit}
+
+		err := c.SetMode(&mode)
+		if err != nil {
+			t.Fatalf("SetMode(%+v): %v", mode, err)
+		}
+		if got := server.Mode(); got != mode {
+			t.Errorf("server mode = %+v, want %+v", got, mode)
+		}
+	}
+}
+
+func TestPurge(t *testing.T) {
+	_, port, address := startServer(t)
+	c := dial(t, address, &serial.Mode{BaudRate: 115200})
+
+	port.out.Write([]byte("stale\r\n"))
+	eventually(t, func() bool {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.buf.Len() > 0
+	})
+
+	err := c.ResetInputBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.ResetOutputBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	port.mu.Lock()
+	purgedRx, purgedTx := port.purgedRx, port.purgedTx
+	port.mu.Unlock()
+	if purgedRx != 1 || purgedTx != 1 {
+		t.Errorf("purged rx %d, tx %d times, want 1 each", purgedRx, purgedTx)
+	}
+
+	c.SetReadTimeout(50 * time.Millisecond)
+	n, err := c.Read(make([]byte, 16))
+	if n != 0 || err != nil {
+		t.Errorf("Read after purge = %d, %v, want no data", n, err)
+	}
+}
+
+func TestDataIsEscaped(t *testing.T) {
+	_, port, address := startServer(t)
+	c := dial(t, address, &serial.Mode{BaudRate: 115200})
+
+	sent := []byte{0x01, iac, 0x02, iac, iac}
+	_, err := c.Write(sent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool {
+		port.mu.Lock()
+		defer port.mu.Unlock()
+		return bytes.Equal(port.received.Bytes(), sent)
+	})
+
+	go port.out.Write(sent)
+	c.SetReadTimeout(2 * time.Second)
+	got := make([]byte, len(sent))
+	_, err = io.ReadFull(c, got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, sent) {
+		t.Errorf("read %X, want %X", got, sent)
+	}
+}
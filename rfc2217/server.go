@@ -0,0 +1,191 @@
+package rfc2217
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+
+	"go.bug.st/serial"
+)
+
+// Port is the serial port exported by a Server. serial.Port satisfies it,
+// as does the simulated module in the sim package.
+type Port interface {
+	io.ReadWriter
+	SetMode(mode *serial.Mode) error
+	ResetInputBuffer() error
+	ResetOutputBuffer() error
+}
+
+// Server exports a single port to one client at a time. It is a minimal
+// stand-in for ser2net and similar servers.
+type Server struct {
+	Port Port
+
+	mu     sync.Mutex
+	mode   serial.Mode
+	client net.Conn
+	once   sync.Once
+}
+
+func NewServer(port Port) *Server {
+	return &Server{
+		Port: port,
+		mode: serial.Mode{BaudRate: 115200, DataBits: 8},
+	}
+}
+
+// Mode returns the line settings last requested by a client.
+func (s *Server) Mode() serial.Mode {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mode
+}
+
+func (s *Server) Serve(ln net.Listener) error {
+	s.once.Do(func() { go s.forwardPort() })
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		s.serveConn(conn)
+	}
+}
+
+// forwardPort copies data from the port to the connected client for the
+// lifetime of the server, since a port read cannot be interrupted.
+func (s *Server) forwardPort() {
+	buf := make([]byte, 1024)
+	for {
+		n, err := s.Port.Read(buf)
+		if n > 0 {
+			s.mu.Lock()
+			if s.client != nil {
+				s.client.Write(escape(buf[:n]))
+			}
+			s.mu.Unlock()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	s.mu.Lock()
+	s.client = conn
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.client = nil
+		s.mu.Unlock()
+	}()
+
+	d := &decoder{
+		onVerb: func(verb, opt byte) {
+			switch verb {
+			case will:
+				if opt == optComPort || opt == optBinary {
+					s.reply([]byte{iac, do, opt})
+				} else {
+					s.reply([]byte{iac, dont, opt})
+				}
+			case do:
+				if opt == optBinary || opt == optSGA {
+					s.reply([]byte{iac, will, opt})
+				} else {
+					s.reply([]byte{iac, wont, opt})
+				}
+			}
+		},
+		onSub: s.handleCommand,
+	}
+
+	raw := make([]byte, 1024)
+	var data []byte
+	for {
+		n, err := conn.Read(raw)
+		data = d.decode(raw[:n], data[:0])
+		if len(data) > 0 {
+			s.Port.Write(data)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) reply(p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != nil {
+		s.client.Write(p)
+	}
+}
+
+func (s *Server) handleCommand(sub []byte) {
+	if len(sub) < 2 || sub[0] != optComPort {
+		return
+	}
+
+	cmd, value := sub[1], sub[2:]
+
+	s.mu.Lock()
+	mode := s.mode
+	s.mu.Unlock()
+
+	switch cmd {
+	case setBaudRate:
+		if len(value) == 4 {
+			if baud := binary.BigEndian.Uint32(value); baud != 0 {
+				mode.BaudRate = int(baud)
+			}
+		}
+		value = baudRateValue(mode.BaudRate)
+	case setDataSize:
+		if len(value) == 1 && value[0] != 0 {
+			mode.DataBits = int(value[0])
+		}
+		value = []byte{byte(mode.DataBits)}
+	case setParity:
+		if len(value) == 1 && value[0] != 0 {
+			mode.Parity = parityFromValue(value[0])
+		}
+		value = []byte{parityValue(mode.Parity)}
+	case setStopSize:
+		if len(value) == 1 && value[0] != 0 {
+			mode.StopBits = stopBitsFromValue(value[0])
+		}
+		value = []byte{stopBitsValue(mode.StopBits)}
+	case purgeData:
+		if len(value) == 1 {
+			if value[0]&purgeReceive != 0 {
+				s.Port.ResetInputBuffer()
+			}
+			if value[0]&purgeTransmit != 0 {
+				s.Port.ResetOutputBuffer()
+			}
+		}
+	default:
+		return
+	}
+
+	if cmd != purgeData {
+		s.Port.SetMode(&mode)
+		s.mu.Lock()
+		s.mode = mode
+		s.mu.Unlock()
+	}
+
+	s.reply(subnegotiation(cmd+serverOffset, value))
+}
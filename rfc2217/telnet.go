@@ -0,0 +1,178 @@
+// Package rfc2217 implements the Telnet COM port control option (RFC 2217)
+// so serial ports exported by ser2net, ESP-Link and similar servers can be
+// driven remotely, together with a small server used as a stand-in.
+package rfc2217
+
+import (
+	"encoding/binary"
+
+	"go.bug.st/serial"
+)
+
+const (
+	iac  = 255
+	dont = 254
+	do   = 253
+	wont = 252
+	will = 251
+	sb   = 250
+	se   = 240
+
+	optBinary  = 0
+	optSGA     = 3
+	optComPort = 44
+
+	setBaudRate = 1
+	setDataSize = 2
+	setParity   = 3
+	setStopSize = 4
+	purgeData   = 12
+
+	// servers answer a client command with the command code plus 100
+	serverOffset = 100
+
+	purgeReceive  = 1
+	purgeTransmit = 2
+)
+
+type decoderState int
+
+const (
+	stateData decoderState = iota
+	stateIAC
+	stateOption
+	stateSub
+	stateSubIAC
+)
+
+// decoder splits a Telnet stream into data bytes, option negotiation and
+// subnegotiation. It keeps state between calls so sequences may be split
+// across reads.
+type decoder struct {
+	state  decoderState
+	verb   byte
+	sub    []byte
+	onVerb func(verb, opt byte)
+	onSub  func(sub []byte)
+}
+
+func (d *decoder) decode(in []byte, data []byte) []byte {
+	for _, b := range in {
+		switch d.state {
+		case stateData:
+			if b == iac {
+				d.state = stateIAC
+				continue
+			}
+			data = append(data, b)
+		case stateIAC:
+			switch b {
+			case iac:
+				data = append(data, iac)
+				d.state = stateData
+			case do, dont, will, wont:
+				d.verb = b
+				d.state = stateOption
+			case sb:
+				d.sub = d.sub[:0]
+				d.state = stateSub
+			default:
+				d.state = stateData
+			}
+		case stateOption:
+			if d.onVerb != nil {
+				d.onVerb(d.verb, b)
+			}
+			d.state = stateData
+		case stateSub:
+			if b == iac {
+				d.state = stateSubIAC
+				continue
+			}
+			d.sub = append(d.sub, b)
+		case stateSubIAC:
+			switch b {
+			case se:
+				if d.onSub != nil {
+					d.onSub(append([]byte(nil), d.sub...))
+				}
+				d.state = stateData
+			case iac:
+				d.sub = append(d.sub, iac)
+				d.state = stateSub
+			default:
+				d.state = stateData
+			}
+		}
+	}
+	return data
+}
+
+func escape(p []byte) []byte {
+	out := make([]byte, 0, len(p))
+	for _, b := range p {
+		out = append(out, b)
+		if b == iac {
+			out = append(out, iac)
+		}
+	}
+	return out
+}
+
+func subnegotiation(cmd byte, value []byte) []byte {
+	out := []byte{iac, sb, optComPort, cmd}
+	out = append(out, escape(value)...)
+	return append(out, iac, se)
+}
+
+func baudRateValue(baud int) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(baud))
+}
+
+func parityValue(p serial.Parity) byte {
+	switch p {
+	case serial.OddParity:
+		return 2
+	case serial.EvenParity:
+		return 3
+	case serial.MarkParity:
+		return 4
+	case serial.SpaceParity:
+		return 5
+	}
+	return 1
+}
+
+func parityFromValue(v byte) serial.Parity {
+	switch v {
+	case 2:
+		return serial.OddParity
+	case 3:
+		return serial.EvenParity
+	case 4:
+		return serial.MarkParity
+	case 5:
+		return serial.SpaceParity
+	}
+	return serial.NoParity
+}
+
+func stopBitsValue(s serial.StopBits) byte {
+	switch s {
+	case serial.TwoStopBits:
+		return 2
+	case serial.OnePointFiveStopBits:
+		return 3
+	}
+	return 1
+}
+
+func stopBitsFromValue(v byte) serial.StopBits {
+	switch v {
+	case 2:
+		return serial.TwoStopBits
+	case 3:
+		return serial.OnePointFiveStopBits
+	}
+	return serial.OneStopBit
+}
@@ -2,48 +2,227 @@ package rui3
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
-	"io"
+	"log/slog"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.bug.st/serial"
 )
 
 type RUI3 struct {
-	port   serial.Port
-	reader *bufio.Reader
+	port   Transport
 	writer *bufio.Writer
 
+	lines    chan string
+	raw      atomic.Pointer[RawPort]
+	readDone chan struct{}
+	readErr  error
+	mode     serial.Mode
+	logger   *slog.Logger
+	events   eventBus
+
+	lastCommand  string
 	lastResponse string
+	echo         bool
+	locked       bool
+	password     Secret
+
+	// expectBoot is set while a command that restarts the module runs, so
+	// its banner is not reported as EventRestarted
+	expectBoot atomic.Bool
+
+	firmware Version
+	api      Version
+
+	// commandsMu guards the command list read for Supports
+	commandsMu   sync.Mutex
+	commands     map[string]string
+	commandsRead bool
+
+	// frameCounterMu guards the counters, downlinks are counted from the
+	// event bus
+	frameCounterMu    sync.Mutex
+	frameCounters     FrameCounters
+	frameCounterStore FrameCounterStore
 }
 
-func New(portName string) (*RUI3, error) {
-	mode := &serial.Mode{
-		BaudRate: 115200,
-		Parity:   serial.NoParity,
-		DataBits: 8,
-		StopBits: serial.OneStopBit,
+// New opens a module on a local serial port, or on a remote one when the
+// name is a URL such as tcp://host:port, rfc2217://host:port or
+// unix:///run/rui3.sock. Without options the port runs at 115200 8N1.
+func New(portName string, opts ...Option) (*RUI3, error) {
+	o := newOptions(opts)
+
+	var port Transport
+	var err error
+	if strings.Contains(portName, "://") {
+		port, err = openURL(portName, o.serialMode())
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		port, err = serial.Open(portName, o.serialMode())
+		if err != nil {
+			return nil, fmt.Errorf("failed to open serial port %s: %w", portName, err)
+		}
+	}
+
+	r := newRUI3(port, o)
+
+	err = r.configurePort(o)
+	if err != nil {
+		port.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// NewWithPort wraps an already opened port. Line setting options are not
+// applied, the port is expected to be configured already.
+func NewWithPort(port Transport, opts ...Option) *RUI3 {
+	return newRUI3(port, newOptions(opts))
+}
+
+func newRUI3(port Transport, o options) *RUI3 {
+	if o.capture != nil {
+		port = newCaptureTransport(port, o.capture)
+	}
+
+	r := &RUI3{
+		port:     port,
+		writer:   bufio.NewWriter(port),
+		lines:    make(chan string, 256),
+		readDone: make(chan struct{}),
+		mode:     o.mode,
+		logger:   o.logger,
+	}
+
+	events, _ := r.Subscribe()
+	go r.countDownlinks(events)
+	go r.readLoop()
+
+	return r
+}
+
+func (r *RUI3) configurePort(o options) error {
+	if o.readTimeout > 0 {
+		err := r.port.SetReadTimeout(o.readTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to set read timeout: %w", err)
+		}
+	}
+
+	if mc, ok := transportAs[modemControl](r.port); ok {
+		if o.dtr != nil {
+			err := mc.SetDTR(*o.dtr)
+			if err != nil {
+				return fmt.Errorf("failed to set DTR: %w", err)
+			}
+		}
+		if o.rts != nil {
+			err := mc.SetRTS(*o.rts)
+			if err != nil {
+				return fmt.Errorf("failed to set RTS: %w", err)
+			}
+		}
+	}
+
+	if o.autoBaud {
+		_, err := r.DetectBaudRate(context.Background())
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.password != "" {
+		err := r.Unlock(context.Background(), o.password)
+		if err != nil {
+			return err
+		}
 	}
 
-	port, err := serial.Open(portName, mode)
+	// a module that does not answer yet is used without version checks
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_, err := r.DetectFirmware(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open serial port %s: %w", portName, err)
+		r.logger.Debug("firmware version not detected", "error", err)
 	}
 
-	return &RUI3{
-		port:   port,
-		reader: bufio.NewReader(port),
-		writer: bufio.NewWriter(port),
-	}, nil
+	return nil
 }
 
-func NewWithPort(port serial.Port) *RUI3 {
-	return &RUI3{
-		port:   port,
-		reader: bufio.NewReader(port),
-		writer: bufio.NewWriter(port),
+// readLoop is the only reader of the transport. Lines are handed to
+// recvResponse through r.lines, which is closed when the transport fails,
+// and data read during Raw to its RawPort.
+func (r *RUI3) readLoop() {
+	defer close(r.readDone)
+	defer close(r.lines)
+	defer r.events.close()
+
+	buf := make([]byte, 1024)
+	var pending []byte
+	for {
+		// transports with a read timeout return no data rather than an
+		// error
+		n, err := r.port.Read(buf)
+		if n > 0 {
+			if p := r.raw.Load(); p != nil {
+				p.deliver(bytes.Clone(buf[:n]))
+				pending = nil
+			} else {
+				pending = append(pending, buf[:n]...)
+				for {
+					i := bytes.IndexByte(pending, '\n')
+					if i == -1 {
+						break
+					}
+					r.handleLine(strings.TrimSpace(string(pending[:i])))
+					pending = pending[i+1:]
+				}
+			}
+		}
+		if err != nil {
+			r.readErr = err
+			r.logger.Debug("read failed", "error", err)
+			return
+		}
+	}
+}
+
+func (r *RUI3) handleLine(line string) {
+	if line == "" {
+		return
+	}
+
+	if ev, ok := ParseEvent(line); ok {
+		r.logger.Debug("event", "line", line)
+		r.events.publish(ev)
+	} else {
+		r.logger.Debug("rx", "line", redact(line))
+	}
+
+	if isBootBanner(line) && !r.expectBoot.Swap(false) {
+		r.logger.Debug("module restarted unexpectedly")
+		r.events.publish(Event{Time: time.Now(), Name: EventRestarted, Raw: line})
+	}
+
+	// unsolicited lines pile up when no command is waiting, drop the
+	// oldest rather than blocking the reader
+	select {
+	case r.lines <- line:
+	default:
+		select {
+		case <-r.lines:
+		default:
+		}
+		r.lines <- line
 	}
 }
 
@@ -56,7 +235,18 @@ func (r *RUI3) SetReadTimeout(timeout time.Duration) error {
 }
 
 func (r *RUI3) ResetInputBuffer() error {
-	return r.port.ResetInputBuffer()
+	err := r.port.ResetInputBuffer()
+
+	for {
+		select {
+		case _, ok := <-r.lines:
+			if !ok {
+				return err
+			}
+		default:
+			return err
+		}
+	}
 }
 
 func (r *RUI3) ResetOutputBuffer() error {
@@ -69,6 +259,12 @@ func (r *RUI3) Drain() error {
 
 func (r *RUI3) SendRawCommand(cmd string) error {
 	r.ResetInputBuffer()
+	r.lastCommand = strings.TrimSpace(cmd)
+	r.logger.Debug("tx", "line", redact(r.lastCommand))
+
+	if restarts(r.lastCommand) {
+		r.expectBoot.Store(true)
+	}
 
 	_, err := r.writer.WriteString(cmd + "\r\n")
 	if err != nil {
@@ -78,80 +274,89 @@ func (r *RUI3) SendRawCommand(cmd string) error {
 	return r.writer.Flush()
 }
 
+// restarts reports whether cmd restarts the module, see restartCommands.
+// Queries such as AT+NWM=? do not.
+func restarts(cmd string) bool {
+	return restartCommands[commandName(cmd)] && !strings.HasSuffix(cmd, "?")
+}
+
+// isBootBanner matches the last line of the message the module prints when
+// it starts.
+func isBootBanner(line string) bool {
+	return strings.Contains(line, "Current Work Mode")
+}
+
 func (r *RUI3) RecvResponse(timeout time.Duration) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	response, err := r.recvResponse(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return "", fmt.Errorf("timeout waiting for response after %v", timeout)
+	}
+
+	return response, err
+}
+
+func (r *RUI3) recvResponse(ctx context.Context) (string, error) {
 	var response strings.Builder
 	lines := make([]string, 0)
+	echoed := false
 
 	for {
 		select {
 		case <-ctx.Done():
-			return "", fmt.Errorf("timeout waiting for response after %v", timeout)
-		default:
-			lineChan := make(chan string, 1)
-			errChan := make(chan error, 1)
-
-			go func() {
-				line, err := r.reader.ReadString('\n')
-				if err != nil {
-					errChan <- err
-					return
-				}
-				lineChan <- line
-			}()
-
-			select {
-			case line := <-lineChan:
-				line = strings.TrimSpace(line)
-				if line != "" {
-					lines = append(lines, line)
-					response.WriteString(line)
-					response.WriteString("\n")
-				}
-
-				if strings.Contains(line, "OK") {
-					result := response.String()
-					r.lastResponse = result
-					return result, nil
-				}
-				if strings.Contains(line, "+EVT:TX_DONE") || strings.Contains(line, "+EVT:SEND_CONFIRMED_OK") {
-					result := response.String()
-					r.lastResponse = result
-					return result, nil
-				}
-				if strings.Contains(line, "+EVT:TXP2P DONE") {
-					result := response.String()
-					r.lastResponse = result
-					return result, nil
-				}
-				if strings.Contains(line, "AT_COMMAND_NOT_FOUND") ||
-					strings.Contains(line, "AT_PARAM_ERROR") ||
-					strings.Contains(line, "SEND_CONFIRMED_FAILED") ||
-					strings.Contains(line, "AT_NO_NETWORK_JOINED") {
-					result := response.String()
-					r.lastResponse = result
-					return result, fmt.Errorf("command error: %s", line)
-				}
-			case err := <-errChan:
-				if err == io.EOF {
-					if len(lines) > 0 {
-						result := response.String()
-						r.lastResponse = result
-						return result, nil
-					}
-					continue
-				}
-				continue
-			case <-time.After(100 * time.Millisecond):
+			return "", fmt.Errorf("timeout waiting for response: %w", ctx.Err())
+		case line, ok := <-r.lines:
+			if !ok {
 				if len(lines) > 0 {
 					result := response.String()
 					r.lastResponse = result
 					return result, nil
 				}
+				return "", fmt.Errorf("failed to read response: %w", r.readErr)
+			}
+
+			// modules with echo enabled repeat the command first, drop it so
+			// it is never mistaken for the response
+			if line == r.lastCommand && len(lines) == 0 && !echoed {
+				echoed = true
 				continue
 			}
+			if len(lines) == 0 {
+				r.echo = echoed
+			}
+
+			lines = append(lines, line)
+			response.WriteString(line)
+			response.WriteString("\n")
+
+			if strings.Contains(line, "OK") {
+				result := response.String()
+				r.lastResponse = result
+				return result, nil
+			}
+			if strings.Contains(line, "+EVT:TX_DONE") || strings.Contains(line, "+EVT:SEND_CONFIRMED_OK") {
+				result := response.String()
+				r.lastResponse = result
+				return result, nil
+			}
+			if strings.Contains(line, "+EVT:TXP2P DONE") {
+				result := response.String()
+				r.lastResponse = result
+				return result, nil
+			}
+			if cmdErr := parseCommandError(line); cmdErr != nil {
+				result := response.String()
+				r.lastResponse = result
+				return result, cmdErr
+			}
+		case <-time.After(100 * time.Millisecond):
+			if len(lines) > 0 {
+				result := response.String()
+				r.lastResponse = result
+				return result, nil
+			}
 		}
 	}
 }
@@ -0,0 +1,37 @@
+package rui3
+
+import (
+	"log/slog"
+	"strings"
+)
+
+// Secret holds a key as hex. Printing, logging or encoding it yields
+// "<redacted>", Reveal returns the key itself.
+type Secret string
+
+const redacted = "<redacted>"
+
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return redacted
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(redacted)
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(redacted), nil
+}
+
+// ParseSecret parses a key value as returned by the module.
+func ParseSecret(value string) (Secret, error) {
+	return Secret(strings.ToUpper(value)), nil
+}
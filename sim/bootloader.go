@@ -0,0 +1,155 @@
+package sim
+
+import (
+	"encoding/binary"
+)
+
+// bootloader is the state of the simulated UART bootloader entered with
+// AT+BOOT. Like the RUI3 bootloader it speaks the legacy Nordic serial DFU
+// protocol: SLIP framed HCI packets, each acknowledged with the sequence
+// number expected next. The image is only installed by the stop packet
+// once it is complete and matches the CRC-16 of the init packet.
+type bootloader struct {
+	active   bool
+	expected byte
+	frame    []byte
+	escaped  bool
+	size     int
+	crc      uint16
+	image    []byte
+	failures int
+}
+
+// Firmware returns the last image installed through the bootloader.
+func (m *Module) Firmware() []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.firmware
+}
+
+// FailDFUWrites makes the next n data packets fail their CRC, to test
+// retries.
+func (m *Module) FailDFUWrites(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.boot.failures = n
+}
+
+func handleBoot(m *Module, param string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.boot = bootloader{active: true, expected: 1, failures: m.boot.failures}
+	return []string{"OK"}
+}
+
+// receiveBoot decodes SLIP frames from m.in while the bootloader runs,
+// called with m.mu held. Anything outside a frame is ignored.
+func (m *Module) receiveBoot() {
+	b := &m.boot
+	for len(m.in) > 0 && b.active {
+		c := m.in[0]
+		m.in = m.in[1:]
+
+		switch {
+		case c == 0xC0:
+			if len(b.frame) > 0 {
+				m.bootPacket(b.frame)
+			}
+			b.frame = b.frame[:0]
+			b.escaped = false
+		case b.escaped:
+			b.escaped = false
+			switch c {
+			case 0xDC:
+				b.frame = append(b.frame, 0xC0)
+			case 0xDD:
+				b.frame = append(b.frame, 0xDB)
+			}
+		case c == 0xDB:
+			b.escaped = true
+		default:
+			b.frame = append(b.frame, c)
+		}
+	}
+}
+
+// bootPacket handles one HCI packet. Damaged packets are acknowledged with
+// the unchanged sequence number so the host sends them again, as are
+// repeated ones whose acknowledgement got lost.
+func (m *Module) bootPacket(packet []byte) {
+	b := &m.boot
+	if len(packet) < 6 || packet[0]+packet[1]+packet[2]+packet[3] != 0 {
+		m.bootAck()
+		return
+	}
+	seq := packet[0] & 0x07
+	length := int(packet[1]>>4) | int(packet[2])<<4
+	if len(packet) != 4+length+2 || binary.LittleEndian.Uint16(packet[4+length:]) != crc16(packet[:4+length]) {
+		m.bootAck()
+		return
+	}
+	payload := packet[4 : 4+length]
+	if seq != b.expected || len(payload) < 4 {
+		m.bootAck()
+		return
+	}
+
+	switch binary.LittleEndian.Uint32(payload) {
+	case 3: // start: mode, SoftDevice, bootloader and application size
+		if len(payload) != 20 {
+			break
+		}
+		b.size = int(binary.LittleEndian.Uint32(payload[16:]))
+		b.image = nil
+	case 1: // init packet, its last two bytes before the padding are the CRC-16
+		if len(payload) < 8 {
+			break
+		}
+		b.crc = binary.LittleEndian.Uint16(payload[len(payload)-4:])
+	case 4:
+		if b.failures > 0 {
+			b.failures--
+			m.bootAck()
+			return
+		}
+		b.image = append(b.image, payload[4:]...)
+	case 5:
+		b.expected = (b.expected + 1) % 8
+		m.bootAck()
+		if b.size > 0 && len(b.image) == b.size && crc16(b.image) == b.crc {
+			m.firmware = b.image
+			m.boot = bootloader{failures: b.failures}
+			m.writeLines(m.banner())
+		}
+		return
+	}
+
+	b.expected = (b.expected + 1) % 8
+	m.bootAck()
+}
+
+// bootAck sends an acknowledgement carrying the next expected sequence
+// number. Its header never contains a byte that needs escaping.
+func (m *Module) bootAck() {
+	h := []byte{m.boot.expected << 3, 0, 0, 0}
+	h[3] = -(h[0] + h[1] + h[2])
+	m.out.Write([]byte{0xC0})
+	m.out.Write(h)
+	m.out.Write([]byte{0xC0})
+	m.cond.Broadcast()
+}
+
+func crc16(data []byte) uint16 {
+	crc := uint16(0xFFFF)
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for range 8 {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
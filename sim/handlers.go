@@ -0,0 +1,175 @@
+package sim
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+func (m *Module) registerHandlers() {
+	m.Handle("AT+JOIN", handleJoin)
+	m.Handle("AT+SEND", handleSend)
+	m.Handle("AT+PSEND", handlePSend)
+	m.Handle("AT+PRECV", handlePRecv)
+	m.Handle("ATZ", handleReset)
+	m.Handle("ATR", handleFactoryReset)
+	m.Handle("AT+PWORD", handlePassword)
+	m.Handle("AT+LOCK", handleLock)
+	m.Handle("AT+BOOT", handleBoot)
+	m.Handle("AT?", handleHelp)
+	m.Handle("AT+NWM", handleNetworkMode)
+}
+
+func bootBanner(m *Module) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.banner()
+}
+
+func (m *Module) banner() []string {
+	return []string{
+		"RAKwireless " + m.registers["AT+HWMODEL"].Value + " Example",
+		"------------------------------------------------------",
+		"Version: " + m.registers["AT+VER"].Value,
+		"Current Work Mode: " + workModes[m.registers["AT+NWM"].Value] + ".",
+	}
+}
+
+var workModes = map[string]string{"0": "LoRa P2P", "1": "LoRaWAN", "2": "LoRa FSK"}
+
+// handleNetworkMode restarts the module after a new work mode is set, as
+// the firmware does.
+func handleNetworkMode(m *Module, param string) []string {
+	if param == "?" {
+		return []string{"AT+NWM=" + m.Value("AT+NWM"), "OK"}
+	}
+	if _, ok := workModes[param]; !ok {
+		return []string{"AT_PARAM_ERROR"}
+	}
+
+	// only a different mode restarts the module
+	if param == m.Value("AT+NWM") {
+		return []string{"OK"}
+	}
+	m.SetValue("AT+NWM", param)
+	m.SetValue("AT+NJS", "0")
+	return append([]string{"OK"}, bootBanner(m)...)
+}
+
+func handleReset(m *Module, param string) []string {
+	m.SetValue("AT+NJS", "0")
+	return bootBanner(m)
+}
+
+func handleFactoryReset(m *Module, param string) []string {
+	m.registerGenerated()
+	m.mu.Lock()
+	m.echo = false
+	m.password = ""
+	m.mu.Unlock()
+	return append([]string{"OK"}, bootBanner(m)...)
+}
+
+func handlePassword(m *Module, param string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.locked {
+		if param != m.password {
+			return []string{"AT_PARAM_ERROR"}
+		}
+		m.locked = false
+		return []string{"OK"}
+	}
+
+	if param == "?" || len(param) > 8 {
+		return []string{"AT_PARAM_ERROR"}
+	}
+	m.password = param
+	return []string{"OK"}
+}
+
+func handleLock(m *Module, param string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.password == "" {
+		return []string{"AT_ERROR"}
+	}
+	m.locked = true
+	return []string{"OK"}
+}
+
+// handleHelp lists every command the module knows, like the firmware does
+// for AT?.
+func handleHelp(m *Module, param string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	help := map[string]string{"ATE": commandHelp["ATE"]}
+	for cmd := range m.handlers {
+		if cmd != "AT?" {
+			help[cmd] = commandHelp[cmd]
+		}
+	}
+	for cmd, reg := range m.registers {
+		help[cmd] = reg.Help
+	}
+
+	lines := make([]string, 0, len(help)+1)
+	for _, cmd := range slices.Sorted(maps.Keys(help)) {
+		lines = append(lines, cmd+": "+help[cmd])
+	}
+	return append(lines, "OK")
+}
+
+func handleJoin(m *Module, param string) []string {
+	if param == "?" {
+		return []string{"AT+JOIN=0:0:8:0", "OK"}
+	}
+
+	if !strings.HasPrefix(param, "1") {
+		return []string{"OK"}
+	}
+
+	m.SetValue("AT+NJS", "1")
+	return []string{"OK", "+EVT:JOINED"}
+}
+
+func handleSend(m *Module, param string) []string {
+	if m.Value("AT+NJS") != "1" {
+		return []string{"AT_NO_NETWORK_JOINED"}
+	}
+
+	_, payload, ok := strings.Cut(param, ":")
+	if !ok || ValidateHex(len(payload)/2)(payload) != nil {
+		return []string{"AT_PARAM_ERROR"}
+	}
+
+	upcnt, _ := strconv.ParseUint(m.Value("AT+UPCNT"), 10, 32)
+	m.SetValue("AT+UPCNT", strconv.FormatUint(upcnt+1, 10))
+
+	return []string{"OK", "+EVT:TX_DONE"}
+}
+
+func handlePSend(m *Module, param string) []string {
+	if m.Value("AT+NWM") != "0" || ValidateHex(len(param)/2)(param) != nil || param == "" {
+		return []string{"AT_PARAM_ERROR"}
+	}
+
+	return []string{"OK", "+EVT:TXP2P DONE"}
+}
+
+// handlePRecv only acknowledges the receive window, packets are injected
+// with Emit, e.g. "+EVT:RXP2P:-60:9:AABB".
+func handlePRecv(m *Module, param string) []string {
+	if param == "?" {
+		return []string{"AT+PRECV=0", "OK"}
+	}
+	if m.Value("AT+NWM") != "0" || ValidateInt(0, 65535)(param) != nil {
+		return []string{"AT_PARAM_ERROR"}
+	}
+
+	return []string{"OK"}
+}
@@ -0,0 +1,279 @@
+// Package sim provides a simulated RUI3 module that speaks the AT command
+// protocol over an in-memory serial port.
+package sim
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.bug.st/serial"
+)
+
+type Register struct {
+	Value    string
+	Read     bool
+	Write    bool
+	Validate func(value string) error
+	Help     string
+}
+
+// HandlerFunc handles a command that needs more than a register, such as
+// AT+JOIN or AT+SEND. It receives everything after the '=' and returns the
+// response lines.
+type HandlerFunc func(m *Module, param string) []string
+
+type Module struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	registers map[string]*Register
+	handlers  map[string]HandlerFunc
+	in        []byte
+	out       bytes.Buffer
+	closed    bool
+	echo      bool
+	password  string
+	locked    bool
+	boot      bootloader
+	firmware  []byte
+}
+
+func New() *Module {
+	m := &Module{
+		registers: make(map[string]*Register),
+		handlers:  make(map[string]HandlerFunc),
+	}
+	m.cond = sync.NewCond(&m.mu)
+
+	m.registerGenerated()
+	m.registerHandlers()
+
+	return m
+}
+
+func (m *Module) Define(cmd string, reg Register) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.registers[cmd] = &reg
+}
+
+// Remove drops a register or handler, to simulate module variants without
+// the command.
+func (m *Module) Remove(cmd string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.registers, cmd)
+	delete(m.handlers, cmd)
+}
+
+func (m *Module) Handle(cmd string, h HandlerFunc) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.handlers[cmd] = h
+}
+
+func (m *Module) Value(cmd string) string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if reg, ok := m.registers[cmd]; ok {
+		return reg.Value
+	}
+	return ""
+}
+
+func (m *Module) SetValue(cmd string, value string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if reg, ok := m.registers[cmd]; ok {
+		reg.Value = value
+	}
+}
+
+// Emit queues unsolicited lines, e.g. "+EVT:RX_1:...", for the host to read.
+func (m *Module) Emit(lines ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writeLines(lines)
+}
+
+func (m *Module) writeLines(lines []string) {
+	for _, line := range lines {
+		m.out.WriteString(line)
+		m.out.WriteString("\r\n")
+	}
+	m.cond.Broadcast()
+}
+
+func (m *Module) Read(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for m.out.Len() == 0 && !m.closed {
+		m.cond.Wait()
+	}
+	if m.closed {
+		return 0, io.EOF
+	}
+
+	return m.out.Read(p)
+}
+
+func (m *Module) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		return 0, io.ErrClosedPipe
+	}
+
+	m.in = append(m.in, p...)
+	for {
+		if m.boot.active {
+			m.receiveBoot()
+			break
+		}
+		i := bytes.IndexByte(m.in, '\n')
+		if i == -1 {
+			break
+		}
+		line := strings.TrimSpace(string(m.in[:i]))
+		m.in = m.in[i+1:]
+		if line != "" {
+			if m.echo {
+				m.writeLines([]string{line})
+			}
+			m.writeLines(m.execute(line))
+		}
+	}
+
+	return len(p), nil
+}
+
+func (m *Module) execute(line string) []string {
+	// a locked port stays silent until the password is entered
+	if m.locked && !strings.HasPrefix(line, "AT+PWORD=") {
+		return nil
+	}
+
+	switch line {
+	case "AT":
+		return []string{"OK"}
+	case "ATE":
+		m.echo = !m.echo
+		return []string{"OK"}
+	}
+
+	cmd, param, hasParam := strings.Cut(line, "=")
+
+	// handlers run without the lock so they can use the exported helpers
+	if h, ok := m.handlers[cmd]; ok {
+		m.mu.Unlock()
+		defer m.mu.Lock()
+		return h(m, param)
+	}
+
+	reg, ok := m.registers[cmd]
+	if !ok {
+		return []string{"AT_COMMAND_NOT_FOUND"}
+	}
+
+	switch {
+	case !hasParam:
+		return []string{"AT_PARAM_ERROR"}
+	case param == "?":
+		if !reg.Read {
+			return []string{"AT_PARAM_ERROR"}
+		}
+		return []string{cmd + "=" + reg.Value, "OK"}
+	default:
+		if !reg.Write {
+			return []string{"AT_PARAM_ERROR"}
+		}
+		if reg.Validate != nil && reg.Validate(param) != nil {
+			return []string{"AT_PARAM_ERROR"}
+		}
+		reg.Value = param
+		return []string{"OK"}
+	}
+}
+
+func (m *Module) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	m.cond.Broadcast()
+	return nil
+}
+
+func (m *Module) ResetInputBuffer() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.out.Reset()
+	return nil
+}
+
+func (m *Module) ResetOutputBuffer() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.in = nil
+	return nil
+}
+
+func (m *Module) SetMode(mode *serial.Mode) error      { return nil }
+func (m *Module) Drain() error                         { return nil }
+func (m *Module) SetDTR(dtr bool) error                { return nil }
+func (m *Module) SetRTS(rts bool) error                { return nil }
+func (m *Module) SetReadTimeout(t time.Duration) error { return nil }
+func (m *Module) Break(d time.Duration) error          { return nil }
+func (m *Module) GetModemStatusBits() (*serial.ModemStatusBits, error) {
+	return &serial.ModemStatusBits{}, nil
+}
+
+func ValidateInt(min, max int64) func(string) error {
+	return func(value string) error {
+		v, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return err
+		}
+		if v < min || v > max {
+			return fmt.Errorf("%d out of range %d-%d", v, min, max)
+		}
+		return nil
+	}
+}
+
+func ValidateBool(value string) error {
+	if value != "0" && value != "1" {
+		return fmt.Errorf("not a boolean: %s", value)
+	}
+	return nil
+}
+
+func ValidateHex(length int) func(string) error {
+	return func(value string) error {
+		b, err := hex.DecodeString(value)
+		if err != nil {
+			return err
+		}
+		if len(b) != length {
+			return fmt.Errorf("expected %d bytes, got %d", length, len(b))
+		}
+		return nil
+	}
+}
+
+func ValidateEnum(values ...string) func(string) error {
+	return func(value string) error {
+		for _, v := range values {
+			if v == value {
+				return nil
+			}
+		}
+		return fmt.Errorf("unknown value: %s", value)
+	}
+}
@@ -0,0 +1,324 @@
+// Code generated by atgen from commands.json. DO NOT EDIT.
+
+package sim
+
+import "math"
+
+func (m *Module) registerGenerated() {
+	m.Define("AT+SN", Register{
+		Value:    "1234567890ABCDEF",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "Serial number",
+	})
+	m.Define("AT+VER", Register{
+		Value:    "RUI_4.0.6_RAK3172-E",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "Firmware version",
+	})
+	m.Define("AT+APIVER", Register{
+		Value:    "3.2.6",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "RUI API version",
+	})
+	m.Define("AT+CLIVER", Register{
+		Value:    "1.5.16",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "AT command interface version",
+	})
+	m.Define("AT+HWMODEL", Register{
+		Value:    "rak3172",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "Hardware model",
+	})
+	m.Define("AT+HWID", Register{
+		Value:    "stm32wle5xx",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "Hardware ID",
+	})
+	m.Define("AT+BOOTVER", Register{
+		Value:    "RUI_BOOT_0.6",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "Bootloader version",
+	})
+	m.Define("AT+UID", Register{
+		Value:    "00112233445566778899AABB",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "Chip unique ID",
+	})
+	m.Define("AT+ALIAS", Register{
+		Value:    "",
+		Read:     true,
+		Write:    true,
+		Validate: nil,
+		Help:     "User defined device alias",
+	})
+	m.Define("AT+BUILDTIME", Register{
+		Value:    "Jan 01 2024 00:00:00",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "Firmware build time",
+	})
+	m.Define("AT+REPOINFO", Register{
+		Value:    "RUI3:main",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "Firmware repository information",
+	})
+	m.Define("AT+BAT", Register{
+		Value:    "3.300",
+		Read:     true,
+		Write:    false,
+		Validate: nil,
+		Help:     "Battery voltage in volts",
+	})
+	m.Define("AT+LPM", Register{
+		Value:    "1",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateBool,
+		Help:     "Low power mode",
+	})
+	m.Define("AT+LPMLVL", Register{
+		Value:    "2",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(1, 2),
+		Help:     "Low power mode level",
+	})
+	m.Define("AT+BAUD", Register{
+		Value:    "115200",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(4800, 115200),
+		Help:     "UART baud rate",
+	})
+	m.Define("AT+SLEEP", Register{
+		Value:    "",
+		Read:     false,
+		Write:    true,
+		Validate: ValidateInt(1, 2147483647),
+		Help:     "Sleep for the given milliseconds",
+	})
+	m.Define("AT+DEVEUI", Register{
+		Value:    "AC1F09FFFE000001",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateHex(8),
+		Help:     "Device EUI",
+	})
+	m.Define("AT+APPEUI", Register{
+		Value:    "0000000000000000",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateHex(8),
+		Help:     "Application (join) EUI",
+	})
+	m.Define("AT+APPKEY", Register{
+		Value:    "00000000000000000000000000000000",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateHex(16),
+		Help:     "Application key",
+	})
+	m.Define("AT+NJS", Register{
+		Value:    "0",
+		Read:     true,
+		Write:    false,
+		Validate: ValidateBool,
+		Help:     "Network join status",
+	})
+	m.Define("AT+CFM", Register{
+		Value:    "0",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateBool,
+		Help:     "Confirmed uplink mode",
+	})
+	m.Define("AT+CLASS", Register{
+		Value:    "A",
+		Read:     true,
+		Write:    true,
+		Validate: nil,
+		Help:     "LoRaWAN class",
+	})
+	m.Define("AT+ADR", Register{
+		Value:    "0",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateBool,
+		Help:     "Adaptive data rate",
+	})
+	m.Define("AT+MASK", Register{
+		Value:    "0000",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateHex(2),
+		Help:     "Channel mask",
+	})
+	m.Define("AT+BAND", Register{
+		Value:    "4",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(0, 12),
+		Help:     "Active region",
+	})
+	m.Define("AT+UPCNT", Register{
+		Value:    "0",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(0, 4294967295),
+		Help:     "Uplink frame counter",
+	})
+	m.Define("AT+DOWNCNT", Register{
+		Value:    "0",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(0, 4294967295),
+		Help:     "Downlink frame counter",
+	})
+	m.Define("AT+DR", Register{
+		Value:    "0",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(0, 15),
+		Help:     "Uplink data rate",
+	})
+	m.Define("AT+TXP", Register{
+		Value:    "0",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(0, 15),
+		Help:     "Transmit power index",
+	})
+	m.Define("AT+RETY", Register{
+		Value:    "0",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(0, 7),
+		Help:     "Confirmed uplink retransmissions",
+	})
+	m.Define("AT+RX1DL", Register{
+		Value:    "1",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(1, 15),
+		Help:     "RX1 window delay in seconds",
+	})
+	m.Define("AT+RX2DL", Register{
+		Value:    "2",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(2, 16),
+		Help:     "RX2 window delay in seconds",
+	})
+	m.Define("AT+JN1DL", Register{
+		Value:    "5",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(1, 14),
+		Help:     "Join accept RX1 delay in seconds",
+	})
+	m.Define("AT+JN2DL", Register{
+		Value:    "6",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(2, 15),
+		Help:     "Join accept RX2 delay in seconds",
+	})
+	m.Define("AT+RX2DR", Register{
+		Value:    "0",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(0, 15),
+		Help:     "RX2 window data rate",
+	})
+	m.Define("AT+RX2FQ", Register{
+		Value:    "869525000",
+		Read:     true,
+		Write:    false,
+		Validate: ValidateInt(math.MinInt64, math.MaxInt64),
+		Help:     "RX2 window frequency in Hz",
+	})
+	m.Define("AT+PNM", Register{
+		Value:    "1",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateBool,
+		Help:     "Public network mode",
+	})
+	m.Define("AT+NJM", Register{
+		Value:    "1",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateEnum("0", "1"),
+		Help:     "Network join mode",
+	})
+	m.Define("AT+NWM", Register{
+		Value:    "1",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateEnum("0", "1", "2"),
+		Help:     "Network work mode",
+	})
+	m.Define("AT+DEVADDR", Register{
+		Value:    "00000000",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateHex(4),
+		Help:     "Device address",
+	})
+	m.Define("AT+APPSKEY", Register{
+		Value:    "00000000000000000000000000000000",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateHex(16),
+		Help:     "Application session key",
+	})
+	m.Define("AT+NWKSKEY", Register{
+		Value:    "00000000000000000000000000000000",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateHex(16),
+		Help:     "Network session key",
+	})
+	m.Define("AT+LINKCHECK", Register{
+		Value:    "0",
+		Read:     true,
+		Write:    true,
+		Validate: ValidateInt(0, 2),
+		Help:     "Link check mode",
+	})
+}
+
+// commandHelp describes the commands without a register for AT?.
+var commandHelp = map[string]string{
+	"AT":       "Attention",
+	"ATE":      "Toggle command echo",
+	"ATZ":      "Restart the MCU",
+	"ATR":      "Restore factory defaults",
+	"AT+JOIN":  "Join the network (join:auto join:interval:attempts)",
+	"AT+SEND":  "Send an uplink (port:payload)",
+	"AT+PSEND": "Send a P2P packet (payload)",
+	"AT+PRECV": "Open the P2P receive window in milliseconds",
+	"AT+PWORD": "Serial port lock password of up to 8 characters, unlocks a locked port",
+	"AT+LOCK":  "Lock the serial port until the password is entered",
+	"AT+BOOT":  "Enter the UART bootloader for a firmware update",
+}
@@ -0,0 +1,398 @@
+package rui3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConnState is the connection state of a supervised module. Changes are
+// published as events named after the state.
+type ConnState int
+
+const (
+	StateConnected ConnState = iota
+	StateDisconnected
+	StateRecovering
+)
+
+var connStateNames = map[ConnState]string{
+	StateConnected:    "CONNECTED",
+	StateDisconnected: "DISCONNECTED",
+	StateRecovering:   "RECOVERING",
+}
+
+func (s ConnState) String() string {
+	if name, ok := connStateNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("ConnState(%d)", int(s))
+}
+
+// OpenFunc opens a module, e.g. by calling New with a port name.
+type OpenFunc func(ctx context.Context) (*RUI3, error)
+
+// Supervisor keeps a module usable across unplugged ports and restarts.
+// When the transport fails the port is reopened with a growing delay, and
+// after a reopen or an EventRestarted the configuration given to
+// ApplyConfig, or kept by Snapshot, is written again and the module
+// re-joins if it had joined before. Without either nothing is restored.
+type Supervisor struct {
+	open   OpenFunc
+	logger *slog.Logger
+	devEUI string
+	serial string
+
+	// sem serialises access, a module handles one command at a time
+	sem chan struct{}
+
+	mu     sync.Mutex
+	rui    *RUI3
+	state  ConnState
+	config *Config
+	joined bool
+
+	events eventBus
+	done   chan struct{}
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// NewSupervisor opens the module with open, which is called again whenever
+// the port has to be reopened.
+func NewSupervisor(ctx context.Context, open OpenFunc, logger *slog.Logger) (*Supervisor, error) {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+
+	rui, err := open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	devEUI, serial, err := identify(ctx, rui)
+	if err != nil {
+		rui.Close()
+		return nil, err
+	}
+
+	s := &Supervisor{
+		open:   open,
+		logger: logger.With("module", devEUI),
+		devEUI: devEUI,
+		serial: serial,
+		sem:    make(chan struct{}, 1),
+		rui:    rui,
+		done:   make(chan struct{}),
+	}
+
+	// e.g. a module in P2P mode does not know AT+NJS
+	joined, err := Query(ctx, rui, "AT+NJS", ParseBool)
+	if err != nil {
+		s.logger.Debug("join status not read", "error", err)
+	}
+	s.joined = joined
+
+	// subscribed before returning, a restart right away is not missed
+	events, cancel := rui.Subscribe()
+	s.wg.Add(1)
+	go s.watch(rui, events, cancel)
+
+	return s, nil
+}
+
+// identify returns the module's DevEUI, or its serial number when the
+// DevEUI can not be read.
+func identify(ctx context.Context, rui *RUI3) (key, serial string, err error) {
+	serial, serialErr := Query(ctx, rui, "AT+SN", ParseString)
+	devEUI, err := Query(ctx, rui, "AT+DEVEUI", ParseString)
+	if err == nil {
+		return strings.ToUpper(devEUI), serial, nil
+	}
+	if serialErr == nil && serial != "" {
+		return serial, serial, nil
+	}
+	return "", "", fmt.Errorf("failed to identify module: %w", err)
+}
+
+// DevEUI returns the DevEUI the module had when it was first opened. A
+// reopened port has to lead to the same module.
+func (s *Supervisor) DevEUI() string {
+	return s.devEUI
+}
+
+func (s *Supervisor) SerialNumber() string {
+	return s.serial
+}
+
+func (s *Supervisor) State() ConnState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Subscribe returns a channel receiving the module's events and the
+// connection state changes. Unlike RUI3.Subscribe it stays open across
+// reconnects and is only closed by cancel or Close.
+func (s *Supervisor) Subscribe() (<-chan Event, func()) {
+	return s.events.subscribe()
+}
+
+// Do runs fn with exclusive use of the module. It fails with
+// ErrModuleUnavailable while the port is being reopened.
+func (s *Supervisor) Do(ctx context.Context, fn func(r *RUI3) error) error {
+	select {
+	case s.sem <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.sem }()
+
+	s.mu.Lock()
+	rui := s.rui
+	s.mu.Unlock()
+	if rui == nil {
+		return fmt.Errorf("%w: %s is reconnecting", ErrModuleUnavailable, s.devEUI)
+	}
+
+	return fn(rui)
+}
+
+// ApplyConfig applies cfg, see RUI3.ApplyConfig, and keeps it as the
+// configuration to restore. Settings cfg leaves out are not restored.
+func (s *Supervisor) ApplyConfig(ctx context.Context, cfg *Config) ([]Change, error) {
+	var changes []Change
+	err := s.Do(ctx, func(r *RUI3) error {
+		var err error
+		changes, err = r.ApplyConfig(ctx, cfg)
+		return err
+	})
+	if err != nil {
+		return changes, err
+	}
+
+	applied := *cfg
+	s.mu.Lock()
+	s.config = &applied
+	s.mu.Unlock()
+	return changes, nil
+}
+
+// Snapshot reads the module's configuration and keeps all of it as the
+// configuration to restore, including settings changed through Do.
+func (s *Supervisor) Snapshot(ctx context.Context) error {
+	return s.Do(ctx, func(r *RUI3) error {
+		cfg, err := r.ReadConfig(ctx)
+		if err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.config = cfg
+		return nil
+	})
+}
+
+func (s *Supervisor) setState(state ConnState) {
+	s.mu.Lock()
+	changed := s.state != state
+	s.state = state
+	s.mu.Unlock()
+
+	if changed {
+		s.logger.Info("connection state changed", "state", state)
+		s.events.publish(Event{Time: time.Now(), Name: state.String()})
+	}
+}
+
+// watch forwards the module's events and recovers it when it restarts or
+// its transport fails.
+func (s *Supervisor) watch(rui *RUI3, events <-chan Event, cancel func()) {
+	defer s.wg.Done()
+
+	for {
+		lost := s.forward(rui, events)
+		cancel()
+		if !lost {
+			return
+		}
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+
+		s.logger.Warn("module disconnected", "error", rui.readErr)
+		s.mu.Lock()
+		s.rui = nil
+		s.mu.Unlock()
+		s.setState(StateDisconnected)
+		rui.Close()
+
+		rui, events, cancel = s.reopen()
+		if rui == nil {
+			return
+		}
+	}
+}
+
+// forward reports whether it stopped because the transport failed.
+func (s *Supervisor) forward(rui *RUI3, events <-chan Event) bool {
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return true
+			}
+			if ev.Name == "JOINED" {
+				s.mu.Lock()
+				s.joined = true
+				s.mu.Unlock()
+			}
+			s.events.publish(ev)
+
+			if ev.Name == EventRestarted {
+				s.setState(StateRecovering)
+				err := s.recover(rui)
+				if err != nil {
+					s.logger.Error("recovery after restart failed", "error", err)
+				}
+				s.setState(StateConnected)
+			}
+		case <-s.done:
+			return false
+		}
+	}
+}
+
+// reopen retries with a growing delay until the same module answers
+// again, and recovers it before it is handed out. The module's events are
+// subscribed before recovery, so the ones it causes are forwarded.
+func (s *Supervisor) reopen() (*RUI3, <-chan Event, func()) {
+	delay := time.Second
+	for {
+		select {
+		case <-s.done:
+			return nil, nil, nil
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, 30*time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		rui, err := s.open(ctx)
+		if err == nil {
+			var key string
+			key, _, err = identify(ctx, rui)
+			if err == nil && key != s.devEUI {
+				err = fmt.Errorf("found module %s instead", key)
+			}
+			if err != nil {
+				rui.Close()
+			}
+		}
+		cancel()
+		if err != nil {
+			s.logger.Debug("reopen failed", "error", err)
+			continue
+		}
+
+		events, cancel := rui.Subscribe()
+		s.setState(StateRecovering)
+		err = s.recover(rui)
+		if err != nil {
+			s.logger.Error("recovery after reconnect failed", "error", err)
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			cancel()
+			rui.Close()
+			return nil, nil, nil
+		}
+		s.rui = rui
+		s.mu.Unlock()
+
+		s.setState(StateConnected)
+		return rui, events, cancel
+	}
+}
+
+// recover unlocks the module, writes the last known configuration and
+// starts a join when the module had joined before but lost the session.
+func (s *Supervisor) recover(rui *RUI3) error {
+	select {
+	case s.sem <- struct{}{}:
+	case <-s.done:
+		return errors.New("supervisor closed")
+	}
+	defer func() { <-s.sem }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if rui.password != "" {
+		err := rui.Unlock(ctx, rui.password)
+		if err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	cfg := s.config
+	joined := s.joined
+	s.mu.Unlock()
+
+	if cfg != nil {
+		changes, err := rui.ApplyConfig(ctx, cfg)
+		for _, change := range changes {
+			s.logger.Info("configuration restored", "change", change)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if !joined {
+		return nil
+	}
+	status, err := Query(ctx, rui, "AT+NJS", ParseBool)
+	if err != nil {
+		return err
+	}
+	if !status {
+		s.logger.Info("re-joining network")
+		return rui.Set(ctx, "AT+JOIN", true, false, 8, 0)
+	}
+
+	return nil
+}
+
+// Close closes the module and stops reopening it. Subscriptions are closed
+// once the supervisor has stopped.
+func (s *Supervisor) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	rui := s.rui
+	s.rui = nil
+	s.mu.Unlock()
+
+	var err error
+	if rui != nil {
+		err = rui.Close()
+	}
+
+	s.wg.Wait()
+	s.events.close()
+	return err
+}
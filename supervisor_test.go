@@ -0,0 +1,140 @@
+package rui3_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"tencorvids/rui3-go"
+	"tencorvids/rui3-go/sim"
+)
+
+// waitEvent waits for the event named name, failing the test after wait.
+func waitEvent(t *testing.T, events <-chan rui3.Event, name string, wait time.Duration) {
+	t.Helper()
+	timeout := time.After(wait)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatalf("events closed while waiting for %s", name)
+			}
+			if ev.Name == name {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("no %s event within %v", name, wait)
+		}
+	}
+}
+
+// recovered waits until the supervisor has recovered the module.
+func recovered(t *testing.T, events <-chan rui3.Event, wait time.Duration) {
+	t.Helper()
+	waitEvent(t, events, rui3.StateRecovering.String(), wait)
+	waitEvent(t, events, rui3.StateConnected.String(), wait)
+}
+
+func openSim(m *sim.Module) rui3.OpenFunc {
+	return func(ctx context.Context) (*rui3.RUI3, error) {
+		return rui3.NewWithPort(m), nil
+	}
+}
+
+func supervise(t *testing.T, open rui3.OpenFunc) (*rui3.Supervisor, <-chan rui3.Event) {
+	t.Helper()
+	sup, err := rui3.NewSupervisor(context.Background(), open, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sup.Close() })
+	events, cancel := sup.Subscribe()
+	t.Cleanup(cancel)
+	return sup, events
+}
+
+func TestSupervisorRestoresConfigAfterRestart(t *testing.T) {
+	m := sim.New()
+	sup, events := supervise(t, openSim(m))
+
+	class := rui3.ClassC
+	_, err := sup.ApplyConfig(context.Background(), &rui3.Config{Class: &class})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the restart loses the class, as if it had not been saved
+	m.SetValue("AT+CLASS", "A")
+	m.Write([]byte("ATZ\r\n"))
+	recovered(t, events, 2*time.Second)
+
+	if got := m.Value("AT+CLASS"); got != "C" {
+		t.Errorf("AT+CLASS = %q after recovery, want the applied C", got)
+	}
+}
+
+func TestSupervisorRestoresNothingWithoutConfig(t *testing.T) {
+	m := sim.New()
+	_, events := supervise(t, openSim(m))
+
+	m.SetValue("AT+CLASS", "B")
+	m.Write([]byte("ATZ\r\n"))
+	recovered(t, events, 2*time.Second)
+
+	if got := m.Value("AT+CLASS"); got != "B" {
+		t.Errorf("AT+CLASS = %q after recovery, want B left alone", got)
+	}
+}
+
+func TestSupervisorRejoinsAfterRestart(t *testing.T) {
+	m := sim.New()
+	m.SetValue("AT+NJS", "1")
+	_, events := supervise(t, openSim(m))
+
+	// ATZ drops the session
+	m.Write([]byte("ATZ\r\n"))
+	recovered(t, events, 2*time.Second)
+	waitEvent(t, events, "JOINED", 2*time.Second)
+
+	if got := m.Value("AT+NJS"); got != "1" {
+		t.Errorf("AT+NJS = %q after recovery, want the module joined again", got)
+	}
+}
+
+func TestSupervisorRestoresConfigAfterReopen(t *testing.T) {
+	// the reopened port leads to the same module, which has lost its
+	// configuration
+	var mu sync.Mutex
+	modules := []*sim.Module{sim.New(), sim.New()}
+	opened := 0
+	open := func(ctx context.Context) (*rui3.RUI3, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		m := modules[min(opened, len(modules)-1)]
+		opened++
+		return rui3.NewWithPort(m), nil
+	}
+	sup, events := supervise(t, open)
+
+	adr := true
+	_, err := sup.ApplyConfig(context.Background(), &rui3.Config{ADR: &adr})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modules[0].Close()
+	waitEvent(t, events, rui3.StateDisconnected.String(), 2*time.Second)
+	recovered(t, events, 5*time.Second)
+
+	if got := modules[1].Value("AT+ADR"); got != "1" {
+		t.Errorf("AT+ADR = %q on the reopened module, want the applied 1", got)
+	}
+	err = sup.Do(context.Background(), func(r *rui3.RUI3) error {
+		_, err := r.GetDevEUI()
+		return err
+	})
+	if err != nil {
+		t.Errorf("Do after reopen: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package rui3
+
+import (
+	"context"
+	"errors"
+	"strings"
+)
+
+// ListCommands reads the command list the module prints for AT?, or for
+// AT+HELP on firmware without it, and returns the help text by command.
+// The list is kept for Supports.
+func (r *RUI3) ListCommands(ctx context.Context) (map[string]string, error) {
+	commands, err := r.readCommands(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	r.commandsMu.Lock()
+	defer r.commandsMu.Unlock()
+	r.commands = commands
+	r.commandsRead = true
+	return commands, nil
+}
+
+func (r *RUI3) readCommands(ctx context.Context) (map[string]string, error) {
+	response, err := r.Exec(ctx, "AT?")
+	if errors.Is(err, ErrCommandNotFound) {
+		response, err = r.Exec(ctx, "AT+HELP")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return parseCommandList(response), nil
+}
+
+// supportedCommands returns the command list, reading it on first use. It
+// is nil when the module did not print one.
+func (r *RUI3) supportedCommands() map[string]string {
+	r.commandsMu.Lock()
+	defer r.commandsMu.Unlock()
+
+	if !r.commandsRead {
+		r.commandsRead = true
+
+		ctx, cancel := context.WithTimeout(context.Background(), defaultTimeout)
+		defer cancel()
+		commands, err := r.readCommands(ctx)
+		if err != nil {
+			r.logger.Debug("command list not read, assuming every command is supported", "error", err)
+		}
+		r.commands = commands
+	}
+
+	return r.commands
+}
+
+// forgetCommands makes Supports read the list again, e.g. after a firmware
+// update.
+func (r *RUI3) forgetCommands() {
+	r.commandsMu.Lock()
+	defer r.commandsMu.Unlock()
+	r.commands = nil
+	r.commandsRead = false
+}
+
+// parseCommandList reads "AT+SN: get the serial number" lines. Names are
+// reported with or without a trailing "?" or "=?" depending on the release.
+func parseCommandList(response string) map[string]string {
+	commands := make(map[string]string)
+	for line := range strings.Lines(response) {
+		line = strings.TrimSpace(line)
+		name, help, ok := strings.Cut(line, ":")
+		if !ok || !strings.HasPrefix(name, "AT") {
+			continue
+		}
+
+		name = strings.TrimSpace(name)
+		name = strings.TrimSuffix(name, "?")
+		name = strings.TrimSuffix(name, "=")
+		if name == "" || strings.ContainsAny(name, " =") {
+			continue
+		}
+		commands[name] = strings.TrimSpace(help)
+	}
+	return commands
+}
+
+// Supports reports whether the module implements cmd, e.g. "AT+LPMLVL".
+// Commands newer than the detected firmware are not supported. The first
+// call reads the command list, see ListCommands. When the module does not
+// print one every other command is assumed to be supported.
+func (r *RUI3) Supports(cmd string) bool {
+	if r.checkFirmware(cmd) != nil {
+		return false
+	}
+	if cmd == "AT" {
+		return true
+	}
+
+	commands := r.supportedCommands()
+	if commands == nil {
+		return true
+	}
+	_, ok := commands[cmd]
+	return ok
+}
@@ -1,186 +1,233 @@
 package rui3
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"time"
 )
 
-func (r *RUI3) ResetMCU() error {
+const resetTimeout = 15 * time.Second
+
+// ResetMCU restarts the module with ATZ and waits until it is ready again.
+// It returns the boot message printed by the module. Without a deadline on
+// ctx it waits up to 15 seconds.
+func (r *RUI3) ResetMCU(ctx context.Context) (string, error) {
 	err := r.SendRawCommand("ATZ")
 	if err != nil {
-		return fmt.Errorf("failed to send ATZ command: %w", err)
+		return "", fmt.Errorf("failed to send ATZ command: %w", err)
 	}
 
-	time.Sleep(5 * time.Second)
-
-	return nil
+	return r.waitReady(ctx)
 }
 
-func (r *RUI3) ResetFactoryDefaults() error {
+// ResetFactoryDefaults restores the factory settings with ATR and waits
+// until the module is ready again.
+func (r *RUI3) ResetFactoryDefaults(ctx context.Context) (string, error) {
 	err := r.SendRawCommand("ATR")
 	if err != nil {
-		return fmt.Errorf("failed to send ATR command: %w", err)
+		return "", fmt.Errorf("failed to send ATR command: %w", err)
 	}
 
-	time.Sleep(5 * time.Second)
-
-	return nil
+	return r.waitReady(ctx)
 }
 
-func (r *RUI3) Reset() error {
-	err := r.ResetMCU()
+func (r *RUI3) Reset(ctx context.Context) (string, error) {
+	_, err := r.ResetMCU(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to reset MCU: %w", err)
+		return "", fmt.Errorf("failed to reset MCU: %w", err)
 	}
 
-	err = r.ResetFactoryDefaults()
+	banner, err := r.ResetFactoryDefaults(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to reset factory defaults: %w", err)
+		return banner, fmt.Errorf("failed to reset factory defaults: %w", err)
 	}
 
-	return nil
+	return banner, nil
 }
 
-func (r *RUI3) Attention() (bool, error) {
-	err := r.SendRawCommand("AT")
-	if err != nil {
-		return false, fmt.Errorf("failed to send AT command: %w", err)
-	}
+// waitReady collects the boot message until the module reports its work
+// mode. Firmware that prints no banner is polled with AT once the port has
+// been quiet for a second.
+func (r *RUI3) waitReady(ctx context.Context) (string, error) {
+	ctx, cancel := withDefaultTimeout(ctx, resetTimeout)
+	defer cancel()
+	// firmware without a banner never clears it in readLoop
+	defer r.expectBoot.Store(false)
+
+	var banner strings.Builder
+	booting := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return banner.String(), fmt.Errorf("module not ready: %w", ctx.Err())
+		case line, ok := <-r.lines:
+			if !ok {
+				return banner.String(), fmt.Errorf("failed to read boot message: %w", r.readErr)
+			}
+			// ATR acknowledges before restarting
+			if line == "OK" && !booting {
+				continue
+			}
+			booting = true
+			banner.WriteString(line)
+			banner.WriteString("\n")
+			if isBootBanner(line) {
+				return banner.String(), nil
+			}
+		case <-time.After(time.Second):
+			err := r.SendRawCommand("AT")
+			if err != nil {
+				return banner.String(), fmt.Errorf("failed to send AT command: %w", err)
+			}
 
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return false, fmt.Errorf("failed to receive AT response: %w", err)
+			probeCtx, probeCancel := context.WithTimeout(ctx, 500*time.Millisecond)
+			response, _ := r.recvResponse(probeCtx)
+			probeCancel()
+
+			for line := range strings.SplitSeq(response, "\n") {
+				if line == "" || line == "OK" {
+					continue
+				}
+				banner.WriteString(line)
+				banner.WriteString("\n")
+			}
+			if strings.Contains(response, "OK") || isBootBanner(response) {
+				return banner.String(), nil
+			}
+		}
 	}
+}
 
-	if strings.Contains(response, "OK") {
-		return true, nil
+func (r *RUI3) Attention() (bool, error) {
+	response, err := r.Exec(context.Background(), "AT")
+	if err != nil {
+		return false, err
 	}
 
-	return false, nil
+	return strings.Contains(response, "OK"), nil
 }
 
 func (r *RUI3) GetSerialNumber() (string, error) {
-	err := r.SendRawCommand("AT+SN=?")
-	if err != nil {
-		return "", fmt.Errorf("failed to send AT+SN? command: %w", err)
-	}
+	return Query(context.Background(), r, "AT+SN", ParseString)
+}
 
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return "", fmt.Errorf("failed to receive AT+SN response: %w", err)
-	}
+func (r *RUI3) GetFirmwareVersion() (string, error) {
+	return Query(context.Background(), r, "AT+VER", ParseString)
+}
 
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "AT+SN=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1]), nil
-			}
-		}
-	}
+func (r *RUI3) GetAPIVersion() (string, error) {
+	return Query(context.Background(), r, "AT+APIVER", ParseString)
+}
 
-	return "", fmt.Errorf("failed to receive AT+SN response")
+func (r *RUI3) GetHardwareModel() (string, error) {
+	return Query(context.Background(), r, "AT+HWMODEL", ParseString)
 }
 
-func (r *RUI3) GetFirmwareVersion() (string, error) {
-	err := r.SendRawCommand("AT+VER=?")
-	if err != nil {
-		return "", fmt.Errorf("failed to send AT+VER? command: %w", err)
+func (r *RUI3) GetBootloaderVersion() (string, error) {
+	return Query(context.Background(), r, "AT+BOOTVER", ParseString)
+}
+
+func (r *RUI3) Sleep(ctx context.Context, d time.Duration) error {
+	if d < time.Millisecond {
+		return fmt.Errorf("invalid sleep duration: %v", d)
 	}
 
-	response, err := r.RecvResponse(5 * time.Second)
+	err := r.Set(ctx, "AT+SLEEP", d.Milliseconds())
 	if err != nil {
-		return "", fmt.Errorf("failed to receive AT+VER response: %w", err)
+		return err
 	}
 
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "AT+VER=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1]), nil
-			}
-		}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
 	}
 
-	return "", fmt.Errorf("failed to receive AT+VER response")
+	return r.Wake(ctx)
 }
 
-func (r *RUI3) GetAPIVersion() (string, error) {
-	err := r.SendRawCommand("AT+APIVER=?")
-	if err != nil {
-		return "", fmt.Errorf("failed to send AT+APIVER? command: %w", err)
-	}
-
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return "", fmt.Errorf("failed to receive AT+APIVER response: %w", err)
-	}
+// Wake pokes the module with AT until it answers. The first command after
+// sleep is often swallowed while the UART comes back up, so a single AT is
+// not enough.
+func (r *RUI3) Wake(ctx context.Context) error {
+	for {
+		attemptCtx, cancel := context.WithTimeout(ctx, time.Second)
+		response, err := r.Exec(attemptCtx, "AT")
+		cancel()
+		if err == nil && strings.Contains(response, "OK") {
+			return nil
+		}
 
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "AT+APIVER=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1]), nil
-			}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("module did not wake up: %w", ctx.Err())
+		case <-time.After(200 * time.Millisecond):
 		}
 	}
+}
 
-	return "", fmt.Errorf("failed to receive AT+APIVER response")
+func (r *RUI3) SetLowPowerMode(enabled bool) error {
+	return r.Set(context.Background(), "AT+LPM", enabled)
 }
 
-func (r *RUI3) GetHardwareModel() (string, error) {
-	err := r.SendRawCommand("AT+HWMODEL=?")
-	if err != nil {
-		return "", fmt.Errorf("failed to send AT+HWMODEL? command: %w", err)
-	}
+func (r *RUI3) GetLowPowerMode() (bool, error) {
+	return Query(context.Background(), r, "AT+LPM", ParseBool)
+}
 
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return "", fmt.Errorf("failed to receive AT+HWMODEL response: %w", err)
+type LowPowerLevel int
+
+const (
+	LowPowerStop1 LowPowerLevel = 1
+	LowPowerStop2 LowPowerLevel = 2
+)
+
+func (r *RUI3) SetLowPowerModeLevel(level LowPowerLevel) error {
+	if level < LowPowerStop1 || level > LowPowerStop2 {
+		return fmt.Errorf("invalid low power level: %d", level)
 	}
 
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "AT+HWMODEL=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1]), nil
-			}
-		}
+	return r.Set(context.Background(), "AT+LPMLVL", int(level))
+}
+
+func (r *RUI3) GetLowPowerModeLevel() (LowPowerLevel, error) {
+	return Query(context.Background(), r, "AT+LPMLVL", ParseEnum(map[string]LowPowerLevel{
+		"1": LowPowerStop1,
+		"2": LowPowerStop2,
+	}))
+}
+
+// GetEcho reports whether the module echoes commands. RUI3 has no query
+// for it, so it is detected from the response to AT.
+func (r *RUI3) GetEcho() (bool, error) {
+	_, err := r.Exec(context.Background(), "AT")
+	if err != nil {
+		return false, err
 	}
 
-	return "", fmt.Errorf("failed to receive AT+HWMODEL response")
+	return r.echo, nil
 }
 
-func (r *RUI3) GetBootloaderVersion() (string, error) {
-	err := r.SendRawCommand("AT+BOOTVER=?")
+// SetEcho enables or disables command echo. ATE toggles the setting, so
+// the current state is checked first.
+func (r *RUI3) SetEcho(enabled bool) error {
+	echo, err := r.GetEcho()
 	if err != nil {
-		return "", fmt.Errorf("failed to send AT+BLVER? command: %w", err)
+		return err
+	}
+	if echo == enabled {
+		return nil
 	}
 
-	response, err := r.RecvResponse(5 * time.Second)
+	response, err := r.Exec(context.Background(), "ATE")
 	if err != nil {
-		return "", fmt.Errorf("failed to receive AT+BLVER response: %w", err)
+		return err
 	}
 
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.Contains(line, "AT+BOOTVER=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1]), nil
-			}
-		}
+	if strings.Contains(response, "OK") {
+		return nil
 	}
 
-	return "", fmt.Errorf("failed to receive AT+BLVER response")
+	return fmt.Errorf("failed to set echo: %s", response)
 }
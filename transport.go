@@ -0,0 +1,128 @@
+package rui3
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"tencorvids/rui3-go/rfc2217"
+
+	"go.bug.st/serial"
+)
+
+// Transport is the byte stream a module is attached to. serial.Port
+// satisfies it, so does anything wrapped by NewNetTransport or
+// NewStreamTransport.
+type Transport interface {
+	io.ReadWriteCloser
+	SetReadTimeout(timeout time.Duration) error
+	ResetInputBuffer() error
+	ResetOutputBuffer() error
+	Drain() error
+}
+
+func openURL(address string, mode *serial.Mode) (Transport, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %s: %w", address, err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		conn, err := net.Dial("tcp", u.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", u.Host, err)
+		}
+		return NewNetTransport(conn), nil
+	case "unix":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		conn, err := net.Dial("unix", path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to %s: %w", path, err)
+		}
+		return NewNetTransport(conn), nil
+	case "rfc2217":
+		conn, err := rfc2217.Dial(u.Host, mode)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+
+	return nil, fmt.Errorf("unsupported transport: %s", u.Scheme)
+}
+
+type netTransport struct {
+	conn net.Conn
+	// readTimeout is set by callers while readLoop reads it
+	readTimeout atomic.Int64
+}
+
+// NewNetTransport runs the protocol over a network connection such as a
+// ser2net TCP port or a Unix socket.
+func NewNetTransport(conn net.Conn) Transport {
+	return &netTransport{conn: conn}
+}
+
+func (t *netTransport) Read(p []byte) (int, error) {
+	if timeout := time.Duration(t.readTimeout.Load()); timeout > 0 {
+		err := t.conn.SetReadDeadline(time.Now().Add(timeout))
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := t.conn.Read(p)
+	// a read timeout is not an error on a serial port either, it just
+	// returns no data
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return n, nil
+	}
+
+	return n, err
+}
+
+func (t *netTransport) Write(p []byte) (int, error) {
+	return t.conn.Write(p)
+}
+
+func (t *netTransport) Close() error {
+	return t.conn.Close()
+}
+
+func (t *netTransport) SetReadTimeout(timeout time.Duration) error {
+	t.readTimeout.Store(int64(timeout))
+	if timeout <= 0 {
+		return t.conn.SetReadDeadline(time.Time{})
+	}
+	return nil
+}
+
+// Bytes already in flight on the network cannot be purged, stale lines are
+// dropped by the client instead.
+func (t *netTransport) ResetInputBuffer() error  { return nil }
+func (t *netTransport) ResetOutputBuffer() error { return nil }
+func (t *netTransport) Drain() error             { return nil }
+
+type streamTransport struct {
+	io.ReadWriteCloser
+}
+
+// NewStreamTransport wraps a plain stream such as an io.Pipe or a PTY.
+// Read timeouts and buffer resets are not supported and are ignored.
+func NewStreamTransport(rwc io.ReadWriteCloser) Transport {
+	return &streamTransport{ReadWriteCloser: rwc}
+}
+
+func (t *streamTransport) SetReadTimeout(timeout time.Duration) error { return nil }
+func (t *streamTransport) ResetInputBuffer() error                    { return nil }
+func (t *streamTransport) ResetOutputBuffer() error                   { return nil }
+func (t *streamTransport) Drain() error                               { return nil }
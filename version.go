@@ -0,0 +1,73 @@
+package rui3
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// Version is a semantic version extracted from RUI3 version strings such
+// as "RUI_4.0.6_RAK3172-E". Raw keeps the string reported by the module.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Raw   string
+}
+
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+func ParseVersion(s string) (Version, error) {
+	match := versionPattern.FindStringSubmatch(s)
+	if match == nil {
+		return Version{Raw: s}, fmt.Errorf("invalid version: %q", s)
+	}
+
+	v := Version{Raw: s}
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		v.Patch, _ = strconv.Atoi(match[3])
+	}
+
+	return v, nil
+}
+
+func (v Version) Compare(other Version) int {
+	switch {
+	case v.Major != other.Major:
+		return cmp.Compare(v.Major, other.Major)
+	case v.Minor != other.Minor:
+		return cmp.Compare(v.Minor, other.Minor)
+	default:
+		return cmp.Compare(v.Patch, other.Patch)
+	}
+}
+
+func (v Version) AtLeast(other Version) bool {
+	return v.Compare(other) >= 0
+}
+
+func (v Version) IsZero() bool {
+	return v.Major == 0 && v.Minor == 0 && v.Patch == 0
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// MarshalText keeps the string reported by the module.
+func (v Version) MarshalText() ([]byte, error) {
+	return []byte(v.Raw), nil
+}
+
+func (v *Version) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*v = Version{}
+		return nil
+	}
+	parsed, err := ParseVersion(string(text))
+	*v = parsed
+	return err
+}
@@ -1,22 +1,18 @@
 package rui3
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
-	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 )
 
 func (r *RUI3) JoinNetwork() error {
-	err := r.SendRawCommand("AT+JOIN=?")
+	response, err := r.Exec(context.Background(), "AT+JOIN=?")
 	if err != nil {
-		return fmt.Errorf("failed to send join command: %w", err)
-	}
-
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to receive join response: %w", err)
+		return err
 	}
 
 	if strings.Contains(response, "OK") {
@@ -35,106 +31,19 @@ func (r *RUI3) JoinNetworkWithParams(join bool, autoJoin bool, retryInterval int
 		return fmt.Errorf("invalid join attempts: %d", joinAttempts)
 	}
 
-	joinCmd := "0"
-	if join {
-		joinCmd = "1"
-	}
-
-	autoJoinCmd := "0"
-	if autoJoin {
-		autoJoinCmd = "1"
-	}
-
-	cmd := fmt.Sprintf("AT+JOIN=%s:%s:%d:%d", joinCmd, autoJoinCmd, retryInterval, joinAttempts)
-
-	err := r.SendRawCommand(cmd)
-	if err != nil {
-		return fmt.Errorf("failed to send join command: %w", err)
-	}
-
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to receive join response: %w", err)
-	}
-
-	if strings.Contains(response, "OK") {
-		return nil
-	}
-
-	return fmt.Errorf("failed to set join network settings: %s", response)
+	return r.Set(context.Background(), "AT+JOIN", join, autoJoin, retryInterval, joinAttempts)
 }
 
 func (r *RUI3) JoinStatus() (bool, error) {
-	err := r.SendRawCommand("AT+NJS=?")
-	if err != nil {
-		return false, fmt.Errorf("failed to send joinstatus command: %w", err)
-	}
-
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return false, fmt.Errorf("failed to receive joinstatus response: %w", err)
-	}
-
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "AT+NJS=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1]) == "1", nil
-			}
-		}
-	}
-
-	return false, fmt.Errorf("NJS not found in response: %s", response)
+	return Query(context.Background(), r, "AT+NJS", ParseBool)
 }
 
 func (r *RUI3) GetConfirmMode() (bool, error) {
-	err := r.SendRawCommand("AT+CFM=?")
-	if err != nil {
-		return false, fmt.Errorf("failed to send cfm command: %w", err)
-	}
-
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return false, fmt.Errorf("failed to receive cfm response: %w", err)
-	}
-
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "AT+CFM=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				return strings.TrimSpace(parts[1]) == "1", nil
-			}
-		}
-	}
-
-	return false, fmt.Errorf("CFM not found in response: %s", response)
+	return Query(context.Background(), r, "AT+CFM", ParseBool)
 }
 
 func (r *RUI3) SetConfirmMode(confirm bool) error {
-	mode := "0"
-	if confirm {
-		mode = "1"
-	}
-
-	err := r.SendRawCommand(fmt.Sprintf("AT+CFM=%s", mode))
-	if err != nil {
-		return fmt.Errorf("failed to send cfm command: %w", err)
-	}
-
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to receive cfm response: %w", err)
-	}
-
-	if strings.Contains(response, "OK") {
-		return nil
-	}
-
-	return fmt.Errorf("failed to set confirm mode: %s", response)
+	return r.Set(context.Background(), "AT+CFM", confirm)
 }
 
 type Class int
@@ -145,96 +54,50 @@ const (
 	ClassC
 )
 
-func (r *RUI3) SetClass(class Class) error {
-	if class < ClassA || class > ClassC {
-		return fmt.Errorf("invalid class: %d", class)
-	}
+var classValues = map[string]Class{
+	"A": ClassA,
+	"B": ClassB,
+	"C": ClassC,
+}
 
-	var classCmd string
-	switch class {
-	case ClassA:
-		classCmd = "A"
-	case ClassB:
-		classCmd = "B"
-	case ClassC:
-		classCmd = "C"
+func (c Class) String() string {
+	if c < ClassA || c > ClassC {
+		return fmt.Sprintf("Class(%d)", int(c))
 	}
+	return string(rune('A' + c))
+}
 
-	err := r.SendRawCommand(fmt.Sprintf("AT+CLASS=%s", classCmd))
-	if err != nil {
-		return fmt.Errorf("failed to send class command: %w", err)
-	}
+func (c Class) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
 
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to receive class response: %w", err)
+func (c *Class) UnmarshalText(text []byte) error {
+	class, ok := classValues[strings.ToUpper(string(text))]
+	if !ok {
+		return fmt.Errorf("unknown class %q", text)
 	}
+	*c = class
+	return nil
+}
 
-	if strings.Contains(response, "OK") {
-		return nil
+func (r *RUI3) SetClass(class Class) error {
+	if class < ClassA || class > ClassC {
+		return fmt.Errorf("invalid class: %d", class)
 	}
 
-	return fmt.Errorf("failed to set class: %s", response)
+	return r.Set(context.Background(), "AT+CLASS", class.String())
 }
 
 func (r *RUI3) GetClass() (Class, error) {
-	err := r.SendRawCommand("AT+CLASS=?")
-	if err != nil {
-		return ClassA, fmt.Errorf("failed to send class command: %w", err)
-	}
-
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return ClassA, fmt.Errorf("failed to receive class response: %w", err)
-	}
-
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "AT+CLASS=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				classValue := strings.TrimSpace(parts[1])
-				if colonIndex := strings.Index(classValue, ":"); colonIndex != -1 {
-					classValue = classValue[:colonIndex]
-				}
-				switch classValue {
-				case "A":
-					return ClassA, nil
-				case "B":
-					return ClassB, nil
-				case "C":
-					return ClassC, nil
-				}
-				return ClassA, fmt.Errorf("invalid class: %s", classValue)
-			}
-		}
-	}
-
-	return ClassA, fmt.Errorf("CLASS not found in response: %s", response)
+	return Query(context.Background(), r, "AT+CLASS", ParseEnum(classValues))
 }
 
 func (r *RUI3) SetAdaptiveDataRate(enabled bool) error {
-	enabledCmd := "0"
-	if enabled {
-		enabledCmd = "1"
-	}
-
-	err := r.SendRawCommand(fmt.Sprintf("AT+ADR=%s", enabledCmd))
-	if err != nil {
-		return fmt.Errorf("failed to send adr command: %w", err)
-	}
-
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to receive adr response: %w", err)
-	}
-
-	if strings.Contains(response, "OK") {
-		return nil
-	}
+	return r.Set(context.Background(), "AT+ADR", enabled)
+}
 
-	return fmt.Errorf("failed to set adaptive data rate: %s", response)
+func (r *RUI3) GetAdaptiveDataRate() (bool, error) {
+	return Query(context.Background(), r, "AT+ADR", ParseBool)
 }
 
 type ChannelMask int
@@ -255,116 +118,35 @@ const (
 	SubBand12  ChannelMask = 12
 )
 
-func (r *RUI3) SetChannelMask(mask ChannelMask) error {
-	// check if mask is valid
-	if mask < SubBandAll || mask > SubBand12 {
-		return fmt.Errorf("invalid channel mask: %d", mask)
-	}
-
-	maskCmd := "0000"
-	switch mask {
-	case SubBand1:
-		maskCmd = "0001"
-	case SubBand2:
-		maskCmd = "0002"
-	case SubBand3:
-		maskCmd = "0004"
-	case SubBand4:
-		maskCmd = "0008"
-	case SubBand5:
-		maskCmd = "0010"
-	case SubBand6:
-		maskCmd = "0020"
-	case SubBand7:
-		maskCmd = "0040"
-	case SubBand8:
-		maskCmd = "0080"
-	case SubBand9:
-		maskCmd = "0100"
-	case SubBand10:
-		maskCmd = "0200"
-	case SubBand11:
-		maskCmd = "0400"
-	case SubBand12:
-		maskCmd = "0800"
-	}
-
-	err := r.SendRawCommand(fmt.Sprintf("AT+MASK=%s", maskCmd))
-	if err != nil {
-		return fmt.Errorf("failed to send cmask command: %w", err)
+func (m ChannelMask) hex() string {
+	if m == SubBandAll {
+		return "0000"
 	}
+	return fmt.Sprintf("%04X", 1<<(m-1))
+}
 
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to receive cmask response: %w", err)
+func parseChannelMask(value string) (ChannelMask, error) {
+	value = firstField(value)
+	for mask := SubBand1; mask <= SubBand12; mask++ {
+		if strings.EqualFold(mask.hex(), value) {
+			return mask, nil
+		}
 	}
+	// 0000, 00FF and any multi sub-band mask mean all channels are enabled
+	return SubBandAll, nil
+}
 
-	if strings.Contains(response, "OK") {
-		return nil
+func (r *RUI3) SetChannelMask(mask ChannelMask) error {
+	// check if mask is valid
+	if mask < SubBandAll || mask > SubBand12 {
+		return fmt.Errorf("invalid channel mask: %d", mask)
 	}
 
-	return fmt.Errorf("failed to set channel mask: %s", response)
+	return r.Set(context.Background(), "AT+MASK", mask.hex())
 }
 
 func (r *RUI3) GetChannelMask() (ChannelMask, error) {
-	err := r.SendRawCommand("AT+MASK=?")
-	if err != nil {
-		return SubBandAll, fmt.Errorf("failed to send cmask command: %w", err)
-	}
-
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return SubBandAll, fmt.Errorf("failed to receive cmask response: %w", err)
-	}
-
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "AT+MASK=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				maskValue := strings.TrimSpace(parts[1])
-				if colonIndex := strings.Index(maskValue, ":"); colonIndex != -1 {
-					maskValue = maskValue[:colonIndex]
-				}
-
-				switch maskValue {
-				case "00FF":
-					return SubBandAll, nil
-				case "0000":
-					return SubBandAll, nil
-				case "0001":
-					return SubBand1, nil
-				case "0002":
-					return SubBand2, nil
-				case "0004":
-					return SubBand3, nil
-				case "0008":
-					return SubBand4, nil
-				case "0010":
-					return SubBand5, nil
-				case "0020":
-					return SubBand6, nil
-				case "0040":
-					return SubBand7, nil
-				case "0080":
-					return SubBand8, nil
-				case "0100":
-					return SubBand9, nil
-				case "0200":
-					return SubBand10, nil
-				case "0400":
-					return SubBand11, nil
-				case "0800":
-					return SubBand12, nil
-				default:
-					return SubBandAll, nil
-				}
-			}
-		}
-	}
-
-	return SubBandAll, fmt.Errorf("MASK not found in response: %s", response)
+	return Query(context.Background(), r, "AT+MASK", parseChannelMask)
 }
 
 type RegionBand int
@@ -385,132 +167,128 @@ const (
 	LA915   RegionBand = 12
 )
 
-func (r *RUI3) SetRegionBand(band RegionBand) error {
-	if band < EU433 || band > LA915 {
-		return fmt.Errorf("invalid region band: %d", band)
+var regionBandNames = []string{
+	EU433:   "EU433",
+	CN470:   "CN470",
+	RU864:   "RU864",
+	IN865:   "IN865",
+	EU868:   "EU868",
+	US915:   "US915",
+	AU915:   "AU915",
+	KR920:   "KR920",
+	AS923:   "AS923",
+	AS923_2: "AS923_2",
+	AS923_3: "AS923_3",
+	AS923_4: "AS923_4",
+	LA915:   "LA915",
+}
+
+func (b RegionBand) String() string {
+	if b < EU433 || b > LA915 {
+		return fmt.Sprintf("RegionBand(%d)", int(b))
 	}
+	return regionBandNames[b]
+}
+
+func (b RegionBand) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
 
-	var bandCmd string
-	switch band {
-	case EU433:
-		bandCmd = "0"
-	case CN470:
-		bandCmd = "1"
-	case RU864:
-		bandCmd = "2"
-	case IN865:
-		bandCmd = "3"
-	case EU868:
-		bandCmd = "4"
-	case US915:
-		bandCmd = "5"
-	case AU915:
-		bandCmd = "6"
-	case KR920:
-		bandCmd = "7"
-	case AS923:
-		bandCmd = "8"
-	case AS923_2:
-		bandCmd = "9"
-	case AS923_3:
-		bandCmd = "10"
-	case AS923_4:
-		bandCmd = "11"
-	case LA915:
-		bandCmd = "12"
+func (b *RegionBand) UnmarshalText(text []byte) error {
+	for band, name := range regionBandNames {
+		if strings.EqualFold(name, string(text)) {
+			*b = RegionBand(band)
+			return nil
+		}
 	}
+	return fmt.Errorf("unknown region band %q", text)
+}
 
-	err := r.SendRawCommand(fmt.Sprintf("AT+BAND=%s", bandCmd))
+func parseRegionBand(value string) (RegionBand, error) {
+	band, err := strconv.Atoi(firstField(value))
 	if err != nil {
-		return fmt.Errorf("failed to send band command: %w", err)
+		return EU433, err
 	}
 
-	response, err := r.RecvResponse(5 * time.Second)
-	if err != nil {
-		return fmt.Errorf("failed to receive band response: %w", err)
+	if RegionBand(band) < EU433 || RegionBand(band) > LA915 {
+		return EU433, fmt.Errorf("unknown region band")
 	}
 
-	if strings.Contains(response, "OK") {
-		return nil
+	return RegionBand(band), nil
+}
+
+func (r *RUI3) SetRegionBand(band RegionBand) error {
+	if band < EU433 || band > LA915 {
+		return fmt.Errorf("invalid region band: %d", band)
 	}
 
-	return fmt.Errorf("failed to set region band: %s", response)
+	return r.Set(context.Background(), "AT+BAND", int(band))
 }
 
 func (r *RUI3) GetRegionBand() (RegionBand, error) {
-	err := r.SendRawCommand("AT+BAND=?")
-	if err != nil {
-		return EU433, fmt.Errorf("failed to send band command: %w", err)
+	return Query(context.Background(), r, "AT+BAND", parseRegionBand)
+}
+
+func (r *RUI3) Send(payload string) error {
+	return r.SendOnPort(1, []byte(payload))
+}
+
+func (r *RUI3) SendOnPort(port int, payload []byte) error {
+	if port < 1 || port > 223 {
+		return fmt.Errorf("invalid port: %d", port)
 	}
 
-	response, err := r.RecvResponse(5 * time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	response, err := r.Exec(ctx, fmt.Sprintf("AT+SEND=%d:%s", port, hex.EncodeToString(payload)))
 	if err != nil {
-		return EU433, fmt.Errorf("failed to receive band response: %w", err)
+		return err
 	}
 
-	lines := strings.SplitSeq(response, "\n")
-	for line := range lines {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "AT+BAND=") {
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				bandValue := strings.TrimSpace(parts[1])
-				if colonIndex := strings.Index(bandValue, ":"); colonIndex != -1 {
-					bandValue = bandValue[:colonIndex]
-				}
-
-				switch bandValue {
-				case "0":
-					return EU433, nil
-				case "1":
-					return CN470, nil
-				case "2":
-					return RU864, nil
-				case "3":
-					return IN865, nil
-				case "4":
-					return EU868, nil
-				case "5":
-					return US915, nil
-				case "6":
-					return AU915, nil
-				case "7":
-					return KR920, nil
-				case "8":
-					return AS923, nil
-				case "9":
-					return AS923_2, nil
-				case "10":
-					return AS923_3, nil
-				case "11":
-					return AS923_4, nil
-				case "12":
-					return LA915, nil
-				}
-			}
-		}
+	if strings.Contains(response, "OK") {
+		return r.trackUplink()
 	}
-	return EU433, fmt.Errorf("BAND not found in response: %s", response)
+
+	return fmt.Errorf("failed to send payload: %s", response)
 }
 
-func (r *RUI3) Send(payload string) error {
-	payload = hex.EncodeToString([]byte(payload))
-	payload = fmt.Sprintf("AT+SEND=1:%s", payload)
-	slog.Info("Sending payload", "payload", payload)
+type Downlink struct {
+	Window  string `json:"window"`
+	RSSI    int    `json:"rssi"`
+	SNR     int    `json:"snr"`
+	Port    int    `json:"port"`
+	Payload []byte `json:"payload"`
+}
 
-	err := r.SendRawCommand(payload)
-	if err != nil {
-		return fmt.Errorf("failed to send payload: %w", err)
+// ParseDownlink decodes a "+EVT:RX_1:-70:8:UNICAST:2:1234" receive event.
+func ParseDownlink(ev Event) (Downlink, bool) {
+	if !strings.HasPrefix(ev.Name, "RX_") || len(ev.Params) < 4 {
+		return Downlink{}, false
 	}
 
-	response, err := r.RecvResponse(30 * time.Second)
+	d := Downlink{Window: strings.TrimPrefix(ev.Name, "RX_")}
+	var err error
+	d.RSSI, err = strconv.Atoi(ev.Params[0])
+	if err != nil {
+		return d, false
+	}
+	d.SNR, err = strconv.Atoi(ev.Params[1])
 	if err != nil {
-		return fmt.Errorf("failed to receive send response: %w", err)
+		return d, false
 	}
 
-	if strings.Contains(response, "OK") {
-		return nil
+	// the payload fields are the last two, RUI3 versions differ in what
+	// comes before them
+	n := len(ev.Params)
+	d.Port, err = strconv.Atoi(ev.Params[n-2])
+	if err != nil {
+		return d, false
+	}
+	d.Payload, err = hex.DecodeString(ev.Params[n-1])
+	if err != nil {
+		return d, false
 	}
 
-	return fmt.Errorf("failed to send payload: %s", response)
+	return d, true
 }